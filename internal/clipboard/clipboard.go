@@ -0,0 +1,34 @@
+// Package clipboard reads and writes the clipboard with the system
+// (atotto/clipboard) or with the OSC 52 escape sequence of the terminal,
+// which works on SSH sessions without the X server.
+package clipboard
+
+import (
+	"github.com/atotto/clipboard"
+)
+
+// Backend is the way to access the clipboard: "auto", "system" or "osc52".
+// "auto" writes with OSC 52 when the system clipboard is not available.
+// It does not read with OSC 52 because the reply of the terminal may come
+// after the timeout and be read by the line editor as keys.
+var Backend = "auto"
+
+// WriteAll sets text into the clipboard.
+func WriteAll(text string) error {
+	if Backend != "osc52" {
+		err := clipboard.WriteAll(text)
+		if err == nil || Backend == "system" {
+			return err
+		}
+	}
+	return writeOSC52(text)
+}
+
+// ReadAll returns the text in the clipboard.
+// OSC 52 is queried only when Backend is "osc52".
+func ReadAll() (string, error) {
+	if Backend == "osc52" {
+		return readOSC52()
+	}
+	return clipboard.ReadAll()
+}
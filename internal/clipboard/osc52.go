@@ -0,0 +1,58 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-tty"
+)
+
+// osc52Timeout is the milliseconds to wait for the reply of the terminal.
+const osc52Timeout = 500
+
+var errNoReply = errors.New("OSC 52: the terminal did not reply. It may not allow to read the clipboard")
+
+// passThrough wraps the escape sequence so that tmux and GNU screen
+// send it to the outer terminal.
+func passThrough(seq string) string {
+	if os.Getenv("TMUX") != "" {
+		return "\x1BPtmux;" + strings.ReplaceAll(seq, "\x1B", "\x1B\x1B") + "\x1B\\"
+	}
+	if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return "\x1BP" + seq + "\x1B\\"
+	}
+	return seq
+}
+
+func writeOSC52(text string) error {
+	tty1, err := tty.Open()
+	if err != nil {
+		return err
+	}
+	defer tty1.Close()
+	seq := "\x1B]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	_, err = io.WriteString(tty1.Output(), passThrough(seq))
+	return err
+}
+
+// parseOSC52 returns the text of the reply `ESC ] 52 ; c ; BASE64 (BEL|ESC \)`
+func parseOSC52(reply string) (string, error) {
+	start := strings.Index(reply, "\x1B]52;")
+	if start < 0 {
+		return "", errNoReply
+	}
+	body := reply[start+5:]
+	if pos := strings.IndexByte(body, ';'); pos >= 0 {
+		body = body[pos+1:]
+	}
+	body = strings.TrimSuffix(body, "\a")
+	body = strings.TrimSuffix(body, "\x1B\\")
+	data, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,42 @@
+package clipboard
+
+import (
+	"testing"
+)
+
+func TestParseOSC52(t *testing.T) {
+	for _, reply := range []string{
+		"\x1B]52;c;aGVsbG8gd29ybGQ=\a",
+		"\x1B]52;c;aGVsbG8gd29ybGQ=\x1B\\",
+		"garbage\x1B]52;p;aGVsbG8gd29ybGQ=\a",
+	} {
+		text, err := parseOSC52(reply)
+		if err != nil {
+			t.Fatalf("%q: %s", reply, err.Error())
+		}
+		if text != "hello world" {
+			t.Fatalf("%q: %q", reply, text)
+		}
+	}
+	if _, err := parseOSC52("\x1B[0n"); err != errNoReply {
+		t.Fatalf("expect errNoReply, but %v", err)
+	}
+}
+
+func TestPassThrough(t *testing.T) {
+	seq := "\x1B]52;c;?\a"
+
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+	if result := passThrough(seq); result != seq {
+		t.Fatalf("xterm: %q", result)
+	}
+	t.Setenv("TERM", "screen-256color")
+	if result := passThrough(seq); result != "\x1BP"+seq+"\x1B\\" {
+		t.Fatalf("screen: %q", result)
+	}
+	t.Setenv("TMUX", "/tmp/tmux-0/default,1,0")
+	if result := passThrough(seq); result != "\x1BPtmux;\x1B\x1B]52;c;?\a\x1B\\" {
+		t.Fatalf("tmux: %q", result)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+// +build !windows
+
+package clipboard
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-tty"
+)
+
+// readOSC52 asks the terminal the text of the clipboard.
+// Many terminals do not reply by default for security.
+func readOSC52() (string, error) {
+	tty1, err := tty.Open()
+	if err != nil {
+		return "", err
+	}
+	defer tty1.Close()
+	clean, err := tty1.Raw()
+	if err != nil {
+		return "", err
+	}
+	defer clean()
+
+	if _, err := io.WriteString(tty1.Output(), passThrough("\x1B]52;c;?\a")); err != nil {
+		return "", err
+	}
+	// The file of go-tty is in the blocking mode and can not time out,
+	// so the terminal is opened again to read the reply.
+	in, err := os.Open("/dev/tty")
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	deadline := time.Now().Add(osc52Timeout * time.Millisecond)
+	var reply strings.Builder
+	var buffer [4096]byte
+	for {
+		if err := in.SetReadDeadline(deadline); err != nil {
+			return "", err
+		}
+		n, err := in.Read(buffer[:])
+		reply.Write(buffer[:n])
+		s := reply.String()
+		if strings.Contains(s, "\x1B]52;") && (strings.HasSuffix(s, "\a") || strings.HasSuffix(s, "\x1B\\")) {
+			return parseOSC52(s)
+		}
+		if err != nil {
+			return "", errNoReply
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package clipboard
+
+import (
+	"errors"
+)
+
+// readOSC52 is not available because the console of Windows
+// can not read the reply of the terminal without the line editor.
+func readOSC52() (string, error) {
+	return "", errors.New("OSC 52: reading the clipboard is not supported on Windows")
+}
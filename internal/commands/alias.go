@@ -33,3 +33,19 @@ func cmdAlias(ctx context.Context, cmd Param) (int, error) {
 	}
 	return 0, nil
 }
+
+func aliasRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	records := []Record{}
+	if len(cmd.Args()) <= 1 {
+		for p := alias.Table.Front(); p != nil; p = p.Next() {
+			records = append(records, Record{{"name", p.Key}, {"value", p.Value.String()}})
+		}
+		return records, nil
+	}
+	for _, name := range cmd.Args()[1:] {
+		if val, ok := alias.Table.Load(name); ok {
+			records = append(records, Record{{"name", name}, {"value", val.String()}})
+		}
+	}
+	return records, nil
+}
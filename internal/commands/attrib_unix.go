@@ -5,9 +5,106 @@ package commands
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
+// On Unix, the attributes are mapped as below.
+//
+//	R (read-only): no write permission for anyone
+//	H (hidden)   : the name starts with `.`
+//
+// H is only reported. H, A (archive) and S (system) can not be changed.
+
+func isHiddenName(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
+
+func globfile(pattern string) []string {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil
+	}
+	result := []string{}
+	for _, m := range matches {
+		if stat, err := os.Stat(m); err == nil && !stat.IsDir() {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// setReadOnly removes all the write permissions, and
+// clearReadOnly gives them where the read permissions exist except umask.
+func setReadOnly(path string, readOnly bool) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	bits := unixMode(stat.Mode())
+	if readOnly {
+		bits &^= 0222
+	} else {
+		bits |= ((bits & 0444) >> 1) &^ umask()
+		if bits&0222 == 0 {
+			bits |= 0200
+		}
+	}
+	return os.Chmod(path, fileMode(bits))
+}
+
 func cmdAttrib(ctx context.Context, cmd Param) (int, error) {
-	return 1, errors.New("not supported")
+	var setRO, resetRO bool
+	files := make([]string, 0, len(cmd.Args())-1)
+
+	for _, arg1 := range cmd.Args()[1:] {
+		if len(arg1) == 2 && (arg1[0] == '+' || arg1[0] == '-') {
+			on := arg1[0] == '+'
+			switch arg1[1] {
+			case 'r', 'R':
+				setRO, resetRO = on, !on
+				continue
+			case 'h', 'H', 'a', 'A', 's', 'S':
+				return 1, fmt.Errorf("%s: not supported on this platform", arg1)
+			}
+		}
+		if arg1s := globfile(arg1); len(arg1s) > 0 {
+			files = append(files, arg1s...)
+		} else {
+			files = append(files, arg1)
+		}
+	}
+	if len(files) <= 0 {
+		// `*` of filepath.Glob matches the names starting with `.` too.
+		files = globfile("*")
+	}
+	sort.Strings(files)
+	for _, arg1 := range files {
+		stat, err := os.Stat(arg1)
+		if err != nil {
+			return 1, err
+		}
+		if !setRO && !resetRO {
+			fullpath, err := filepath.Abs(arg1)
+			if err != nil {
+				fullpath = arg1
+			}
+			readOnly, hidden := ' ', ' '
+			if stat.Mode().Perm()&0222 == 0 {
+				readOnly = 'R'
+			}
+			if isHiddenName(arg1) {
+				hidden = 'H'
+			}
+			fmt.Fprintf(cmd.Out(), "    %c%c       %s\n", hidden, readOnly, fullpath)
+			continue
+		}
+		if err := setReadOnly(arg1, setRO); err != nil {
+			return 2, err
+		}
+	}
+	return 0, nil
 }
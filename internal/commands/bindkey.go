@@ -8,13 +8,22 @@ import (
 	"github.com/nyaosorg/go-readline-ny/nameutils"
 )
 
+// BindKeySymbol binds the function named `funcName` to the key named `key`.
+func BindKeySymbol(km *readline.KeyMap, key, funcName string) error {
+	f, err := nameutils.GetFunc(funcName)
+	if err != nil {
+		return err
+	}
+	return nameutils.BindKeyFunc(km, key, f)
+}
+
 func cmdBindkey(ctx context.Context, cmd Param) (int, error) {
 	if len(cmd.Args()) < 3 {
 		fmt.Fprintf(cmd.Err(), "%[1]s: Usage %[1]s KEYNAME FUNCNAME\n",
 			cmd.Arg(0))
 		return 0, nil
 	}
-	err := nameutils.BindKeySymbol(readline.GlobalKeyMap, cmd.Arg(1), cmd.Arg(2))
+	err := BindKeySymbol(readline.GlobalKeyMap, cmd.Arg(1), cmd.Arg(2))
 	if err != nil {
 		return 1, err
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/nyaosorg/go-windows-shortcut"
 
 	"github.com/nyaosorg/nyagos/internal/nodos"
+	"github.com/nyaosorg/nyagos/internal/shell"
 )
 
 var cdHistory = make([]string, 0, 100)
@@ -36,6 +37,31 @@ func pushCdHistory() {
 	}
 }
 
+// isInteractive reports whether cmd is typed on the prompt.
+func isInteractive(cmd Param) bool {
+	c, ok := cmd.(*shell.Cmd)
+	return ok && InteractiveStream != nil && c.Stream == InteractiveStream
+}
+
+// visitDirectory records the current directory for `j`
+// when the directory is changed by the command typed on the prompt.
+func visitDirectory(cmd Param) {
+	if isInteractive(cmd) {
+		visitCdRank()
+	}
+}
+
+// cdWithHistory pushes the current directory to the history and changes
+// the directory.
+func cdWithHistory(cmd Param, dir string) (int, error) {
+	pushCdHistory()
+	rc, err := cmdCdSub(dir)
+	if err == nil {
+		visitDirectory(cmd)
+	}
+	return rc, err
+}
+
 const (
 	errnoChdirFail = 1
 	errnoNoHistory = 2
@@ -94,8 +120,7 @@ func cmdCd(ctx context.Context, cmd Param) (int, error) {
 
 			}
 			directory := cdHistory[len(cdHistory)-1]
-			pushCdHistory()
-			return cmdCdSub(directory)
+			return cdWithHistory(cmd, directory)
 		} else if args[1] == "--history" {
 			dir, err := os.Getwd()
 			if err == nil {
@@ -107,6 +132,14 @@ func cmdCd(ctx context.Context, cmd Param) (int, error) {
 				fmt.Fprintln(cmd.Out(), cdHistory[i])
 			}
 			return 0, nil
+		} else if args[1] == "-i" {
+			directory, err := pickDirectory(args[2:])
+			if err != nil || directory == "" {
+				return errnoChdirFail, err
+			}
+			return cdWithHistory(cmd, directory)
+		} else if args[1] == "-z" {
+			return cdRankJump(cmd, args[2:])
 		} else if args[1] == "-h" || args[1] == "?" {
 			i := len(cdHistory) - 10
 			if i < 0 {
@@ -122,20 +155,17 @@ func cmdCd(ctx context.Context, cmd Param) (int, error) {
 				return errnoNoHistory, fmt.Errorf("cd %s: too old history", args[1])
 			}
 			directory := cdHistory[i]
-			pushCdHistory()
-			return cmdCdSub(directory)
+			return cdWithHistory(cmd, directory)
 		}
 		if strings.EqualFold(args[1], "/D") {
 			// ignore /D
 			args = args[1:]
 		}
-		pushCdHistory()
-		return cmdCdSub(strings.Join(args[1:], " "))
+		return cdWithHistory(cmd, strings.Join(args[1:], " "))
 	}
 	home := nodos.GetHome()
 	if home != "" {
-		pushCdHistory()
-		return cmdCdSub(home)
+		return cdWithHistory(cmd, home)
 	}
 	return cmdPwd(ctx, cmd)
 }
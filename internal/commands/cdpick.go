@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mattn/go-tty"
+)
+
+// pickCandidates returns the directory stack, the history of cd,
+// the directories recorded for `j` and the subdirectories of %CDPATH%
+// without duplicates and the current directory.
+func pickCandidates() []string {
+	wd, _ := os.Getwd()
+	result := []string{}
+	uniq := map[string]struct{}{}
+	add := func(dir string) {
+		key := dir
+		if runtime.GOOS == "windows" {
+			key = strings.ToUpper(dir)
+		}
+		if _, ok := uniq[key]; ok || sameDir(dir, wd) {
+			return
+		}
+		uniq[key] = struct{}{}
+		result = append(result, dir)
+	}
+	for i := len(dirstack) - 1; i >= 0; i-- {
+		add(dirstack[i])
+	}
+	for i := len(cdHistory) - 1; i >= 0; i-- {
+		add(cdHistory[i])
+	}
+	if ranked, err := findCdRank(nil); err == nil {
+		for _, e := range ranked {
+			add(e.Path)
+		}
+	}
+	for _, cdpath1 := range filepath.SplitList(os.Getenv("CDPATH")) {
+		if files, err := os.ReadDir(cdpath1); err == nil {
+			for _, f := range files {
+				if f.IsDir() {
+					add(filepath.Join(cdpath1, f.Name()))
+				}
+			}
+		}
+	}
+	return result
+}
+
+// filterDirs returns the directories which contain all the keywords
+// (case-insensitive).
+func filterDirs(dirs []string, keywords []string) []string {
+	result := []string{}
+	for _, dir := range dirs {
+		lower := strings.ToLower(dir)
+		matched := true
+		for _, k := range keywords {
+			if !strings.Contains(lower, strings.ToLower(k)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, dir)
+		}
+	}
+	return result
+}
+
+type dirPicker struct {
+	tty    *tty.TTY
+	out    io.Writer
+	dirs   []string
+	query  []rune
+	items  []string
+	cursor int
+	top    int
+}
+
+// filter narrows the directories with the words typed.
+func (p *dirPicker) filter() {
+	p.items = filterDirs(p.dirs, strings.Fields(string(p.query)))
+	p.cursor = 0
+	p.top = 0
+}
+
+func (p *dirPicker) draw() {
+	width, height, err := p.tty.Size()
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 25
+	}
+	rows := height - 2
+	if rows < 1 {
+		rows = 1
+	}
+	if p.cursor < p.top {
+		p.top = p.cursor
+	} else if p.cursor >= p.top+rows {
+		p.top = p.cursor - rows + 1
+	}
+
+	var buffer strings.Builder
+	buffer.WriteString("\x1B[H")
+	buffer.WriteString(cutWidth(fmt.Sprintf("%d/%d> %s", len(p.items), len(p.dirs), string(p.query)), width-1))
+	buffer.WriteString("\x1B[K\r\n")
+	for i := p.top; i < p.top+rows; i++ {
+		if i < len(p.items) {
+			if i == p.cursor {
+				buffer.WriteString("\x1B[7m")
+			}
+			buffer.WriteString(cutWidth(p.items[i], width-1))
+			buffer.WriteString("\x1B[0m")
+		}
+		buffer.WriteString("\x1B[K\r\n")
+	}
+	buffer.WriteString("\x1B[7m")
+	buffer.WriteString(cutWidth("Type to filter Up/Down:move Enter:select Esc:cancel", width-1))
+	buffer.WriteString("\x1B[0m\x1B[K")
+	io.WriteString(p.out, buffer.String())
+}
+
+// isPrintable reports whether the key is the text typed (or pasted).
+func isPrintable(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if c < ' ' || c == 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+// run lets the user narrow the list and select a directory.
+// It returns "" when canceled.
+func (p *dirPicker) run() (string, error) {
+	for {
+		p.draw()
+		key, err := readKey(p.tty)
+		if err != nil {
+			return "", err
+		}
+		_, height, err := p.tty.Size()
+		if err != nil || height < 4 {
+			height = 4
+		}
+		page := height - 2
+		switch key {
+		case "\x1B", "\x03", "\x07":
+			return "", nil
+		case "\r", "\n":
+			if p.cursor < len(p.items) {
+				return p.items[p.cursor], nil
+			}
+		case "\x1B[A", "\x10":
+			p.cursor--
+		case "\x1B[B", "\x0E":
+			p.cursor++
+		case "\x1B[5~":
+			p.cursor -= page
+		case "\x1B[6~":
+			p.cursor += page
+		case "\x7F", "\b":
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+		case "\x15":
+			p.query = p.query[:0]
+			p.filter()
+		default:
+			if isPrintable(key) {
+				p.query = append(p.query, []rune(key)...)
+				p.filter()
+			}
+		}
+		if p.cursor >= len(p.items) {
+			p.cursor = len(p.items) - 1
+		}
+		if p.cursor < 0 {
+			p.cursor = 0
+		}
+	}
+}
+
+// pickDirectory lets the user select a directory on the menu.
+// The list is narrowed by the keywords and then by the words typed.
+// It returns "" when canceled.
+func pickDirectory(keywords []string) (string, error) {
+	dirs := pickCandidates()
+	if matched := filterDirs(dirs, keywords); len(matched) <= 1 {
+		if len(matched) == 1 {
+			return matched[0], nil
+		}
+		if len(keywords) > 0 {
+			return "", fmt.Errorf("%s: no directory matches", strings.Join(keywords, " "))
+		}
+		return "", errors.New("no directory to choose")
+	}
+
+	tty1, err := tty.Open()
+	if err != nil {
+		return "", err
+	}
+	defer tty1.Close()
+	out := tty1.Output()
+
+	restore, err := tty1.Raw()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	// the alternate screen and the hidden cursor
+	io.WriteString(out, "\x1B[?1049h\x1B[?25l")
+	defer io.WriteString(out, "\x1B[?25h\x1B[?1049l")
+
+	p := &dirPicker{tty: tty1, out: out, dirs: dirs}
+	if len(keywords) > 0 {
+		p.query = []rune(strings.Join(keywords, " ") + " ")
+	}
+	p.filter()
+	return p.run()
+}
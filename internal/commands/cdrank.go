@@ -0,0 +1,385 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nyaosorg/nyagos/internal/completion"
+	"github.com/nyaosorg/nyagos/internal/shell"
+)
+
+// CdRankPath is the file which records the directories visited by `cd`
+// with their visit counts and the last times. It is not recorded when empty.
+var CdRankPath = ""
+
+// InteractiveStream is the stream which reads the command-lines typed
+// on the prompt. Only the directories changed by them are recorded,
+// not by scripts, blocks and `foreach`.
+var InteractiveStream shell.Stream
+
+// cdRankAging is the total of ranks to make all ranks 0.9 times (same as z.sh)
+const cdRankAging = 9000
+
+type cdRankEntry struct {
+	Path string
+	Rank float64
+	Time time.Time
+}
+
+// frecency returns the rank weighted by how recently the directory was visited.
+func (e *cdRankEntry) frecency(now time.Time) float64 {
+	age := now.Sub(e.Time)
+	switch {
+	case age < time.Hour:
+		return e.Rank * 4
+	case age < 24*time.Hour:
+		return e.Rank * 2
+	case age < 7*24*time.Hour:
+		return e.Rank / 2
+	default:
+		return e.Rank / 4
+	}
+}
+
+// loadCdRank reads the lines of `PATH<TAB>RANK<TAB>UNIXTIME`
+func loadCdRank() ([]*cdRankEntry, error) {
+	if CdRankPath == "" {
+		return nil, nil
+	}
+	fd, err := os.Open(CdRankPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fd.Close()
+	entries := []*cdRankEntry{}
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		field := strings.Split(sc.Text(), "\t")
+		if len(field) < 3 {
+			continue
+		}
+		rank, err := strconv.ParseFloat(field[1], 64)
+		if err != nil {
+			continue
+		}
+		stamp, err := strconv.ParseInt(field[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, &cdRankEntry{
+			Path: field[0],
+			Rank: rank,
+			Time: time.Unix(stamp, 0),
+		})
+	}
+	return entries, sc.Err()
+}
+
+// lockCdRank creates the lock file not to lose the changes by other nyagos
+// between loading and saving. The lock left by the crashed one is removed
+// when it is older than cdRankLockStale.
+func lockCdRank() (func(), error) {
+	lock := CdRankPath + ".lock"
+	for i := 0; ; i++ {
+		fd, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+		if err == nil {
+			fd.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if stat, err := os.Stat(lock); err == nil && time.Since(stat.ModTime()) > cdRankLockStale {
+			os.Remove(lock)
+			continue
+		}
+		if i >= 100 {
+			return nil, fmt.Errorf("%s: locked", lock)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+const cdRankLockStale = 10 * time.Second
+
+// updateCdRank applies update to the entries read from the file just now
+// and writes them back, so that the changes by other nyagos are merged.
+func updateCdRank(update func([]*cdRankEntry) ([]*cdRankEntry, error)) error {
+	unlock, err := lockCdRank()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := loadCdRank()
+	if err != nil {
+		return err
+	}
+	entries, err = update(entries)
+	if err != nil {
+		return err
+	}
+	return saveCdRank(entries)
+}
+
+// saveCdRank writes to the temporary file and renames it,
+// so that other nyagos never read the file half written.
+func saveCdRank(entries []*cdRankEntry) error {
+	tmp := fmt.Sprintf("%s.%d", CdRankPath, os.Getpid())
+	fd, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fd)
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%g\t%d\n", e.Path, e.Rank, e.Time.Unix())
+	}
+	err = w.Flush()
+	if err1 := fd.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, CdRankPath)
+}
+
+func sameDir(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// visitCdRank increases the rank of the current directory.
+func visitCdRank() {
+	if CdRankPath == "" {
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	updateCdRank(func(entries []*cdRankEntry) ([]*cdRankEntry, error) {
+		found := false
+		total := 0.0
+		for _, e := range entries {
+			if sameDir(e.Path, wd) {
+				e.Rank++
+				e.Time = time.Now()
+				found = true
+			}
+			total += e.Rank
+		}
+		if !found {
+			entries = append(entries, &cdRankEntry{Path: wd, Rank: 1, Time: time.Now()})
+		}
+		if total > cdRankAging {
+			aged := entries[:0]
+			for _, e := range entries {
+				e.Rank *= 0.9
+				if e.Rank >= 1 {
+					aged = append(aged, e)
+				}
+			}
+			entries = aged
+		}
+		return entries, nil
+	})
+}
+
+// matchCdRank tests that keywords appear in path in order
+// and the last keyword appears in the last element of path.
+func matchCdRank(path string, keywords []string) bool {
+	path = strings.ToLower(path)
+	pos := 0
+	for _, k := range keywords {
+		k = strings.ToLower(k)
+		i := strings.Index(path[pos:], k)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(k)
+	}
+	if len(keywords) <= 0 {
+		return true
+	}
+	last := strings.ToLower(keywords[len(keywords)-1])
+	return strings.Contains(strings.ToLower(filepath.Base(path)), last)
+}
+
+// findCdRank returns the existing directories matching keywords
+// by the order of frecency. The current directory is not included.
+func findCdRank(keywords []string) ([]*cdRankEntry, error) {
+	entries, err := loadCdRank()
+	if err != nil {
+		return nil, err
+	}
+	wd, _ := os.Getwd()
+	result := []*cdRankEntry{}
+	for _, e := range entries {
+		if sameDir(e.Path, wd) || !matchCdRank(e.Path, keywords) {
+			continue
+		}
+		if stat, err := os.Stat(e.Path); err != nil || !stat.IsDir() {
+			continue
+		}
+		result = append(result, e)
+	}
+	now := time.Now()
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].frecency(now) > result[j].frecency(now)
+	})
+	return result, nil
+}
+
+// cdRankJump changes the directory to the best match of keywords.
+// A keyword which is an absolute path of a directory (completed) is used as it is.
+func cdRankJump(cmd Param, keywords []string) (int, error) {
+	if len(keywords) > 0 {
+		last := keywords[len(keywords)-1]
+		if stat, err := os.Stat(last); err == nil && stat.IsDir() && filepath.IsAbs(last) {
+			return cdWithHistory(cmd, last)
+		}
+	}
+	found, err := findCdRank(keywords)
+	if err != nil {
+		return errnoChdirFail, err
+	}
+	if len(found) <= 0 {
+		return errnoNoHistory, fmt.Errorf("%s: no directory matches", strings.Join(keywords, " "))
+	}
+	return cdWithHistory(cmd, found[0].Path)
+}
+
+const jUsage = `Usage: j KEYWORD...    change directory to the best match
+       j -l [KEYWORD...] list directories with scores
+       j -x [DIR]        remove DIR (or the current directory) from the list`
+
+func cmdJ(ctx context.Context, cmd Param) (int, error) {
+	args := cmd.Args()[1:]
+	if len(args) <= 0 {
+		args = []string{"-l"}
+	}
+	switch args[0] {
+	case "-l":
+		found, err := findCdRank(args[1:])
+		if err != nil {
+			return 1, err
+		}
+		now := time.Now()
+		for i := len(found) - 1; i >= 0; i-- {
+			fmt.Fprintf(cmd.Out(), "%-10.1f %s\n", found[i].frecency(now), found[i].Path)
+		}
+		return 0, nil
+	case "-x":
+		dir, err := os.Getwd()
+		if len(args) >= 2 {
+			dir, err = filepath.Abs(args[1])
+		}
+		if err != nil {
+			return 1, err
+		}
+		err = updateCdRank(func(entries []*cdRankEntry) ([]*cdRankEntry, error) {
+			rest := entries[:0]
+			for _, e := range entries {
+				if !sameDir(e.Path, dir) {
+					rest = append(rest, e)
+				}
+			}
+			if len(rest) == len(entries) {
+				return nil, fmt.Errorf("%s: not in the list", dir)
+			}
+			return rest, nil
+		})
+		if err != nil {
+			return 1, err
+		}
+		return 0, nil
+	case "-h", "-?":
+		fmt.Fprintln(cmd.Out(), jUsage)
+		return 0, nil
+	}
+	if CdRankPath == "" {
+		return errnoNoHistory, errors.New("j: the directory ranking is not available")
+	}
+	return cdRankJump(cmd, args)
+}
+
+func jRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	args := cmd.Args()[1:]
+	if len(args) > 0 && args[0] == "-l" {
+		args = args[1:]
+	}
+	found, err := findCdRank(args)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	records := make([]Record, 0, len(found))
+	for _, e := range found {
+		records = append(records, Record{
+			{"path", e.Path},
+			{"score", e.frecency(now)},
+			{"rank", e.Rank},
+			{"time", e.Time.Format(time.RFC3339)},
+		})
+	}
+	return records, nil
+}
+
+// completionCdRank completes `j KEYWORD...` and `cd -z KEYWORD...` with the best match.
+// The candidates are not listed because their common prefix would replace the keyword.
+func completionCdRank(ctx context.Context, ua completion.UncCompletion, params []string) ([]completion.Element, error) {
+	keywords := []string{}
+	for _, p := range params[1:] {
+		if p != "-z" && p != "" {
+			keywords = append(keywords, p)
+		}
+	}
+	found, err := findCdRank(keywords)
+	if err != nil || len(found) <= 0 {
+		return nil, err
+	}
+	return []completion.Element{completion.Element1(found[0].Path)}, nil
+}
+
+type cdCompleter struct {
+	completion.CustomCompleter
+}
+
+func (c cdCompleter) Complete(ctx context.Context, ua completion.UncCompletion, params []string) ([]completion.Element, error) {
+	if len(params) >= 3 && params[1] == "-z" {
+		return completionCdRank(ctx, ua, params)
+	}
+	return c.CustomCompleter.Complete(ctx, ua, params)
+}
+
+type jCompleter struct{}
+
+func (jCompleter) Complete(ctx context.Context, ua completion.UncCompletion, params []string) ([]completion.Element, error) {
+	return completionCdRank(ctx, ua, params)
+}
+
+func (jCompleter) String() string {
+	return "Built-in `j` completer"
+}
+
+func init() {
+	completion.CustomCompletion["j"] = &jCompleter{}
+	if cd, ok := completion.CustomCompletion["cd"]; ok {
+		completion.CustomCompletion["cd"] = cdCompleter{CustomCompleter: cd}
+	}
+}
@@ -0,0 +1,90 @@
+package commands_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+	"github.com/nyaosorg/nyagos/internal/shell"
+)
+
+// testPrompt runs the command as if it is typed on the prompt.
+func testPrompt(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := testCommand(t, args...)
+	cmd.Stream = commands.InteractiveStream
+	if rc, _, err := commands.Exec(context.Background(), cmd); err != nil || rc != 0 {
+		t.Fatalf("%v: %d %v", args, rc, err)
+	}
+}
+
+func TestCdRank(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.Chdir(wd)
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	commands.CdRankPath = filepath.Join(dir, "nyagos.dirs")
+	defer func() { commands.CdRankPath = "" }()
+	commands.InteractiveStream = &shell.BufStream{}
+	defer func() { commands.InteractiveStream = nil }()
+
+	projAPI := filepath.Join(dir, "proj", "api")
+	otherAPI := filepath.Join(dir, "other", "api")
+	for _, d := range []string{projAPI, otherAPI, filepath.Join(dir, "other")} {
+		if err := os.MkdirAll(d, 0777); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	for _, d := range []string{projAPI, otherAPI, dir, otherAPI, dir} {
+		testPrompt(t, "cd", d)
+	}
+	// cd in scripts is not recorded.
+	testExec(t, "cd", filepath.Join(dir, "other"))
+	testExec(t, "cd", dir)
+	if records := testRecords(t, "j", "other"); len(records) != 0 {
+		t.Fatalf("j other: %v", records)
+	}
+
+	records := testRecords(t, "j", "api")
+	if len(records) != 2 || records[0].Map()["path"] != otherAPI {
+		t.Fatalf("j api: %v", records)
+	}
+	// The last keyword has to match the last element.
+	if records := testRecords(t, "j", "proj"); len(records) != 0 {
+		t.Fatalf("j proj: %v", records)
+	}
+	testPrompt(t, "cd", "-z", "proj", "api")
+	if wd, _ := os.Getwd(); wd != projAPI {
+		t.Fatalf("cd -z proj api: %s", wd)
+	}
+	testPrompt(t, "j", "api")
+	if wd, _ := os.Getwd(); wd != otherAPI {
+		t.Fatalf("j api: %s", wd)
+	}
+
+	// pushd and popd on the prompt are recorded.
+	pushed := filepath.Join(dir, "pushed")
+	popped := filepath.Join(dir, "popped")
+	for _, d := range []string{pushed, popped} {
+		if err := os.Mkdir(d, 0777); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	testExec(t, "cd", popped)
+	testPrompt(t, "pushd", pushed)
+	testPrompt(t, "popd")
+	testExec(t, "cd", dir)
+	for _, name := range []string{"pushed", "popped"} {
+		if records := testRecords(t, "j", name); len(records) != 1 {
+			t.Fatalf("j %s: %v", name, records)
+		}
+	}
+}
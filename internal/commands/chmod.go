@@ -4,90 +4,322 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
-var rxOOO = regexp.MustCompile("^[0-7][0-7][0-7]$")
-var rxEqu = regexp.MustCompile(`^([aogu]+)([\-\+\=])([rwx]+)$`)
+const chmodUsage = `Usage: chmod [-R] [-v] MODE FILE...
+       chmod [-R] [-v] --reference=RFILE FILE...
+MODE: OCTAL (e.g. 755, 4755) or [ugoa]*[-+=][rwxXst]*|[ugo] separated by commas`
 
-func _cmdChmod(args []string) error {
-	if len(args) < 2 {
-		return errors.New("usage: chmod ooo (files...)")
+// unixMode converts os.FileMode to the bits of chmod(2) like 04755
+func unixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
 	}
-	var f func(string) error
-	if rxOOO.MatchString(args[0]) {
-		val, err := strconv.ParseInt(args[0], 8, 32)
-		if err != nil {
-			return fmt.Errorf("%s: invalid permission str", args[0])
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return bits
+}
+
+// fileMode converts the bits of chmod(2) to os.FileMode
+func fileMode(bits uint32) os.FileMode {
+	mode := os.FileMode(bits & 0777)
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// chmodFunc returns the new mode (the bits of chmod(2))
+// from the current one.
+type chmodFunc func(old uint32, isDir bool) uint32
+
+// parseChmodClause parses one clause of the symbolic mode like `ug+rwX`
+func parseChmodClause(clause string, mask uint32) (chmodFunc, error) {
+	var who uint32
+	i := 0
+who:
+	for ; i < len(clause); i++ {
+		switch clause[i] {
+		case 'u':
+			who |= 04700
+		case 'g':
+			who |= 02070
+		case 'o':
+			who |= 01007
+		case 'a':
+			who |= 07777
+		default:
+			break who
 		}
-		f = func(fname string) error {
-			return os.Chmod(fname, os.FileMode(val))
+	}
+	// Without who, the bits in umask are not added (POSIX).
+	useMask := who == 0
+	if useMask {
+		who = 07777
+	}
+	type action struct {
+		op    byte
+		perm  uint32
+		x     bool   // X: execute only for directories or executable files
+		wants string // u, g, o: copy the permission of them
+	}
+	actions := []action{}
+	if i >= len(clause) {
+		return nil, fmt.Errorf("%s: operator missing", clause)
+	}
+	for i < len(clause) {
+		a := action{op: clause[i]}
+		if a.op != '+' && a.op != '-' && a.op != '=' {
+			return nil, fmt.Errorf("%s: invalid operator `%c`", clause, a.op)
 		}
-	} else if m := rxEqu.FindStringSubmatch(args[0]); m != nil {
-		var basebits os.FileMode
-		for _, r := range m[3] {
-			switch r {
+		for i++; i < len(clause) && !strings.ContainsRune("+-=", rune(clause[i])); i++ {
+			switch clause[i] {
 			case 'r':
-				basebits |= 4
+				a.perm |= 0444
 			case 'w':
-				basebits |= 2
+				a.perm |= 0222
 			case 'x':
-				basebits |= 1
+				a.perm |= 0111
+			case 'X':
+				a.x = true
+			case 's':
+				a.perm |= 06000
+			case 't':
+				a.perm |= 01000
+			case 'u', 'g', 'o':
+				a.wants += string(clause[i])
+			default:
+				return nil, fmt.Errorf("%s: invalid permission `%c`", clause, clause[i])
 			}
 		}
-		var bits os.FileMode
-		for _, r := range m[1] {
-			switch r {
-			case 'u':
-				bits |= (basebits << 6)
-			case 'g':
-				bits |= (basebits << 3)
-			case 'o':
-				bits |= basebits
-			case 'a':
-				bits = (basebits << 6) | (basebits << 3) | basebits
-			}
+		if a.wants != "" && (a.perm != 0 || a.x || len(a.wants) > 1) {
+			return nil, fmt.Errorf("%s: invalid permission", clause)
 		}
-		switch m[2] {
-		case "+":
-			f = func(fname string) error {
-				stat, err := os.Stat(fname)
-				if err != nil {
-					return err
-				}
-				mod := stat.Mode()
-				return os.Chmod(fname, mod|bits)
+		actions = append(actions, a)
+	}
+	return func(mode uint32, isDir bool) uint32 {
+		executable := isDir || mode&0111 != 0
+		for _, a := range actions {
+			perm := a.perm
+			if a.x && executable {
+				perm |= 0111
 			}
-		case "-":
-			f = func(fname string) error {
-				stat, err := os.Stat(fname)
-				if err != nil {
-					return err
-				}
-				mod := stat.Mode()
-				return os.Chmod(fname, mod&^bits)
+			switch a.wants {
+			case "u":
+				perm |= (mode >> 6 & 7) * 0111
+			case "g":
+				perm |= (mode >> 3 & 7) * 0111
+			case "o":
+				perm |= (mode & 7) * 0111
+			}
+			perm &= who
+			if useMask {
+				perm &^= mask
 			}
-		case "=":
-			f = func(fname string) error {
-				return os.Chmod(fname, bits)
+			switch a.op {
+			case '+':
+				mode |= perm
+			case '-':
+				mode &^= perm
+			case '=':
+				clear := who & 0777
+				if !isDir {
+					// setuid and setgid of directories are kept as GNU chmod does.
+					clear |= who & 07000
+				} else {
+					clear |= who & 01000
+				}
+				mode = (mode &^ clear) | perm
 			}
 		}
-	} else {
-		return fmt.Errorf("%s: invalid permission str", args[0])
+		return mode
+	}, nil
+}
+
+// parseChmodMode parses the octal mode or the symbolic mode
+// whose clauses are separated by commas.
+func parseChmodMode(s string, mask uint32) (chmodFunc, error) {
+	if s != "" && strings.Trim(s, "01234567") == "" {
+		if len(s) > 4 {
+			return nil, fmt.Errorf("%s: invalid mode", s)
+		}
+		val, err := strconv.ParseUint(s, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid mode", s)
+		}
+		bits := uint32(val)
+		return func(uint32, bool) uint32 { return bits }, nil
 	}
-	for _, fname := range args[1:] {
-		if err := f(fname); err != nil {
-			return fmt.Errorf("%s: %s", fname, err.Error())
+	clauses := []chmodFunc{}
+	for _, clause := range strings.Split(s, ",") {
+		f, err := parseChmodClause(clause, mask)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, f)
+	}
+	return func(mode uint32, isDir bool) uint32 {
+		for _, f := range clauses {
+			mode = f(mode, isDir)
+		}
+		return mode
+	}, nil
+}
+
+func modeString(bits uint32) string {
+	return fmt.Sprintf("%04o (%s)", bits, fileMode(bits).String()[1:])
+}
+
+type chmodOption struct {
+	recursive bool
+	verbose   bool
+	f         chmodFunc
+}
+
+func (opt *chmodOption) chmod(cmd Param, path string, stat fs.FileInfo) error {
+	old := unixMode(stat.Mode())
+	bits := opt.f(old, stat.IsDir())
+	if bits != old {
+		if err := os.Chmod(path, fileMode(bits)); err != nil {
+			return err
+		}
+	}
+	if opt.verbose {
+		if bits != old {
+			fmt.Fprintf(cmd.Out(), "mode of '%s' changed from %s to %s\n", path, modeString(old), modeString(bits))
+		} else {
+			fmt.Fprintf(cmd.Out(), "mode of '%s' retained as %s\n", path, modeString(old))
 		}
 	}
 	return nil
 }
 
+func (opt *chmodOption) run(cmd Param, path string) int {
+	errorcount := 0
+	report := func(path string, err error) {
+		fmt.Fprintf(cmd.Err(), "%s: %s\n", path, err.Error())
+		errorcount++
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		report(path, err)
+		return errorcount
+	}
+	if err := opt.chmod(cmd, path, stat); err != nil {
+		report(path, err)
+	}
+	if !opt.recursive || !stat.IsDir() {
+		return errorcount
+	}
+	// The symbolic links in the tree are not followed.
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			report(p, err)
+			return nil
+		}
+		if p == path || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		stat, err := d.Info()
+		if err == nil {
+			err = opt.chmod(cmd, p, stat)
+		}
+		if err != nil {
+			report(p, err)
+		}
+		return nil
+	})
+	return errorcount
+}
+
 func cmdChmod(_ context.Context, cmd Param) (int, error) {
-	if err := _cmdChmod(cmd.Args()[1:]); err != nil {
-		return 1, err
+	opt := &chmodOption{}
+	args := cmd.Args()[1:]
+	reference := ""
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		arg := args[0]
+		if arg == "--" {
+			args = args[1:]
+			break
+		}
+		if strings.HasPrefix(arg, "--reference") {
+			if strings.HasPrefix(arg, "--reference=") {
+				reference = arg[len("--reference="):]
+			} else if arg == "--reference" && len(args) >= 2 {
+				reference = args[1]
+				args = args[1:]
+			} else {
+				return 1, errors.New(chmodUsage)
+			}
+			args = args[1:]
+			continue
+		}
+		if arg == "--recursive" {
+			opt.recursive = true
+			args = args[1:]
+			continue
+		}
+		if arg == "--verbose" {
+			opt.verbose = true
+			args = args[1:]
+			continue
+		}
+		if strings.Trim(arg[1:], "Rv") != "" {
+			// `-w`, `-x` and so on are the mode
+			break
+		}
+		for _, c := range arg[1:] {
+			if c == 'R' {
+				opt.recursive = true
+			} else {
+				opt.verbose = true
+			}
+		}
+		args = args[1:]
+	}
+	if reference != "" {
+		stat, err := os.Stat(reference)
+		if err != nil {
+			return 1, err
+		}
+		bits := unixMode(stat.Mode())
+		opt.f = func(uint32, bool) uint32 { return bits }
+	} else {
+		if len(args) <= 0 {
+			return 1, errors.New(chmodUsage)
+		}
+		f, err := parseChmodMode(args[0], umask())
+		if err != nil {
+			return 1, err
+		}
+		opt.f = f
+		args = args[1:]
+	}
+	if len(args) <= 0 {
+		return 1, errors.New(chmodUsage)
+	}
+	errorcount := 0
+	for _, path := range args {
+		errorcount += opt.run(cmd, path)
+	}
+	if errorcount > 0 {
+		return 1, nil
 	}
 	return 0, nil
 }
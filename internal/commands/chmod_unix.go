@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// startupUmask is the file mode creation mask read at the startup.
+// syscall.Umask can read it only by setting it, which is safe only
+// before other goroutines create files.
+var startupUmask = func() uint32 {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return uint32(mask)
+}()
+
+// umask returns the file mode creation mask of the process.
+// It is read from /proc/self/status on Linux without changing it,
+// otherwise the mask at the startup is returned.
+func umask() uint32 {
+	fd, err := os.Open("/proc/self/status")
+	if err != nil {
+		return startupUmask
+	}
+	defer fd.Close()
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "Umask:") {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(line[6:]), 8, 32)
+		if err != nil {
+			break
+		}
+		return uint32(mask)
+	}
+	return startupUmask
+}
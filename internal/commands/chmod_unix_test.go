@@ -0,0 +1,101 @@
+//go:build !windows
+// +build !windows
+
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestChmodSymbolic(t *testing.T) {
+	mask := syscall.Umask(022)
+	defer syscall.Umask(mask)
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, []byte{}, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, c := range []struct {
+		from   os.FileMode
+		mode   string
+		expect os.FileMode
+	}{
+		{0644, "u+x", 0744},
+		{0644, "+x", 0755},
+		{0644, "go-r,u+s", 0600 | os.ModeSetuid},
+		{0644, "g=u", 0664},
+		{0644, "a-w,o=", 0440},
+		{0644, "o+t", 0644 | os.ModeSticky},
+		{0644, "u+X", 0644},
+		{0644, "=rw", 0644},
+		{0644, "2755", 0755 | os.ModeSetgid},
+		{0666, "=r", 0444},
+		{0666, "-w", 0466},
+	} {
+		if err := os.Chmod(file, c.from); err != nil {
+			t.Fatal(err.Error())
+		}
+		testExec(t, "chmod", c.mode, file)
+		stat, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if mode := stat.Mode() &^ os.ModeType; mode != c.expect {
+			t.Errorf("chmod %s: %v, expected %v", c.mode, mode, c.expect)
+		}
+	}
+}
+
+func TestChmodRecursive(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	file := filepath.Join(sub, "file")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.WriteFile(file, []byte{}, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	testExec(t, "chmod", "-R", "go-rx,u+X", dir)
+	for path, expect := range map[string]os.FileMode{dir: 0700, sub: 0700, file: 0600} {
+		if stat, err := os.Stat(path); err != nil || stat.Mode().Perm() != expect {
+			t.Errorf("chmod -R: %s: %v %v", path, stat.Mode(), err)
+		}
+	}
+	testExec(t, "chmod", "--reference="+sub, file)
+	if stat, err := os.Stat(file); err != nil || stat.Mode().Perm() != 0700 {
+		t.Errorf("chmod --reference: %v %v", stat.Mode(), err)
+	}
+}
+
+func TestAttrib(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	hidden := filepath.Join(dir, ".hidden")
+	for _, name := range []string{file, hidden} {
+		if err := os.WriteFile(name, []byte{}, 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	testExec(t, "attrib", "+r", file)
+	if stat, err := os.Stat(file); err != nil || stat.Mode().Perm() != 0444 {
+		t.Fatalf("attrib +r: %v", err)
+	}
+	for _, opt := range []string{"+h", "-h"} {
+		if _, err := testRun(t, nil, "attrib", opt, hidden, file); err == nil {
+			t.Fatalf("attrib %s: no error", opt)
+		}
+	}
+	for _, name := range []string{file, hidden} {
+		if _, err := os.Stat(name); err != nil {
+			t.Fatalf("attrib -h: %v", err)
+		}
+	}
+	expect := "    H        " + hidden + "\n     R       " + file + "\n"
+	if result := testOutput(t, "attrib", hidden, file); result != expect {
+		t.Fatalf("attrib: %q", result)
+	}
+}
@@ -0,0 +1,6 @@
+package commands
+
+// umask returns 0 because Windows has no file mode creation mask.
+func umask() uint32 {
+	return 0
+}
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/nyaosorg/nyagos/internal/clipboard"
+)
+
+const clipUsage = `Usage: COMMAND | clip   ... copy the output of COMMAND to the clipboard
+       clip -o          ... print the text in the clipboard`
+
+func cmdClip(ctx context.Context, cmd Param) (int, error) {
+	args := cmd.Args()[1:]
+	if len(args) >= 1 {
+		if args[0] != "-o" {
+			return 1, errors.New(clipUsage)
+		}
+		text, err := clipboard.ReadAll()
+		if err != nil {
+			return 1, err
+		}
+		io.WriteString(cmd.Out(), text)
+		return 0, nil
+	}
+	text, err := readClipInput(cmd)
+	if err != nil {
+		return 2, err
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
@@ -4,17 +4,10 @@
 package commands
 
 import (
-	"context"
 	"io"
-
-	"github.com/atotto/clipboard"
 )
 
-func cmdClip(ctx context.Context, cmd Param) (int, error) {
+func readClipInput(cmd Param) (string, error) {
 	data, err := io.ReadAll(cmd.In())
-	if err != nil {
-		return 1, err
-	}
-	clipboard.WriteAll(string(data))
-	return 0, nil
+	return string(data), err
 }
@@ -27,6 +27,7 @@ type Param interface {
 	RawArgs() []string
 	Spawnlp(context.Context, []string, []string) (int, error)
 	Spawnlpe(context.Context, []string, []string, map[string]string) (int, error)
+	SpawnWith(context.Context, []string, []string, *shell.SpawnOption) (int, error)
 	Loop(context.Context, shell.Stream) (int, error)
 	ReadCommand(context.Context) (context.Context, string, error)
 	DumpEnv() []string
@@ -38,36 +39,52 @@ var buildInCommand ignoreCaseSorted.Dictionary[func(context.Context, Param) (int
 var unscoNamePattern = regexp.MustCompile("^__(.*)__$")
 var backslashPattern = regexp.MustCompile(`^\\(\w*)$`)
 
+// lookupBuiltin returns the built-in command for name
+// which may be enclosed like `__ls__` or start with a backslash like `\ls`.
+func lookupBuiltin(name string) (string, func(context.Context, Param) (int, error), bool) {
+	if function, ok := buildInCommand.Load(name); ok {
+		return name, function, true
+	}
+	m := unscoNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		m = backslashPattern.FindStringSubmatch(name)
+		if m == nil {
+			return name, nil, false
+		}
+	}
+	function, ok := buildInCommand.Load(m[1])
+	return m[1], function, ok
+}
+
 // Exec is the entry function to call built-in functions from Shell
 func Exec(ctx context.Context, cmd Param) (int, bool, error) {
 	name := cmd.Arg(0)
 	if len(name) == 2 && strings.HasSuffix(name, ":") {
 		pushCdHistory()
 		_, err := nodos.Chdrive(name)
+		if err == nil {
+			visitDirectory(cmd)
+		}
 		return 0, true, err
 	}
-	function, ok := buildInCommand.Load(name)
+	name, function, ok := lookupBuiltin(name)
 	if !ok {
-		m := unscoNamePattern.FindStringSubmatch(name)
-		if m != nil {
-			name = m[1]
-			function, ok = buildInCommand.Load(name)
-			if !ok {
-				return 0, false, nil
-			}
-		} else {
-			n := backslashPattern.FindStringSubmatch(name)
-			if n == nil {
-				return 0, false, nil
+		return 0, false, nil
+	}
+	cmd.SetArgs(findfile.Globs(cmd.Args()))
+	if source, ok := recordCommand.Load(name); ok {
+		if format, args := cutFormatOption(cmd.Args()); format != formatText {
+			cmd.SetArgs(args)
+			records, err := source(ctx, cmd)
+			if err1 := writeRecords(cmd.Out(), format, records); err == nil {
+				err = err1
 			}
-			name = n[1]
-			function, ok = buildInCommand.Load(name)
-			if !ok {
-				return 0, false, nil
+			if err != nil {
+				return 1, true, err
 			}
+			return 0, true, nil
 		}
 	}
-	cmd.SetArgs(findfile.Globs(cmd.Args()))
 	next, err := function(ctx, cmd)
 	return next, true, err
 }
@@ -20,14 +20,18 @@ type copyMoveT struct {
 	Param
 	Action  func(src, dst string) error
 	IsDirOk bool
+	// Copier enables /s, --update and --dry-run of copy
+	Copier *treeCopier
 }
 
 func cmdCopy(ctx context.Context, cmd Param) (int, error) {
+	cp := newTreeCopier(cmd)
 	return copyMoveT{
 		Param: cmd,
 		Action: func(src, dst string) error {
-			return nodos.Copy(src, dst, false)
+			return cp.Copy(ctx, src, dst)
 		},
+		Copier: cp,
 	}.Run(ctx, cmd.Args())
 }
 
@@ -84,17 +88,24 @@ func judgeDir(path string) bool {
 
 func (cm copyMoveT) Run(ctx context.Context, args []string) (int, error) {
 	all := false
+	update := false
+	dryRun := false
 	args = args[1:]
-	for {
-		if len(args) >= 1 && (args[0] == "/y" || args[0] == "/Y") {
+	for len(args) >= 1 {
+		if strings.EqualFold(args[0], "/y") {
 			all = true
-			args = args[1:]
-		} else if len(args) >= 1 && (args[0] == "/-y" || args[0] == "/-Y") {
+		} else if strings.EqualFold(args[0], "/-y") {
 			all = false
-			args = args[1:]
+		} else if cm.Copier != nil && strings.EqualFold(args[0], "/s") {
+			cm.Copier.recursive = true
+		} else if args[0] == "--update" {
+			update = true
+		} else if args[0] == "--dry-run" {
+			dryRun = true
 		} else {
 			break
 		}
+		args = args[1:]
 	}
 	if len(args) < 2 {
 		fmt.Fprintf(cm.Err(),
@@ -103,6 +114,10 @@ func (cm copyMoveT) Run(ctx context.Context, args []string) (int, error) {
 			cm.Arg(0), cm.Arg(0))
 		return 0, nil
 	}
+	if cm.Copier != nil {
+		cm.Copier.update = update
+		cm.Copier.dryRun = dryRun
+	}
 
 	_dst := args[len(args)-1]
 	if strings.ToLower(filepath.Ext(_dst)) == ".lnk" {
@@ -126,12 +141,24 @@ func (cm copyMoveT) Run(ctx context.Context, args []string) (int, error) {
 			}
 			dst = filepath.Join(dst, name)
 		}
-		if !cm.IsDirOk {
-			fi, err := os.Stat(src)
-			if err == nil && fi.Mode().IsDir() {
+		fi, err := os.Stat(src)
+		if err == nil && fi.Mode().IsDir() {
+			if !cm.IsDirOk && (cm.Copier == nil || !cm.Copier.recursive) {
 				fmt.Fprintf(cm.Err(), "%s is directory and passed.\n", src)
 				continue
 			}
+		} else if err == nil && update && isUpToDate(fi, dst) {
+			continue
+		}
+		if dryRun {
+			if cm.Copier != nil {
+				if err := cm.Action(src, dst); err != nil {
+					fmt.Fprintf(cm.Err(), "%s: %s\n", src, err.Error())
+				}
+			} else {
+				fmt.Fprintf(cm.Err(), "(dry-run) %s -> %s\n", src, dst)
+			}
+			continue
 		}
 
 		fmt.Fprintf(cm.Err(), "%s -> %s\n", src, dst)
@@ -170,7 +197,11 @@ func (cm copyMoveT) Run(ctx context.Context, args []string) (int, error) {
 			default:
 			}
 		}
-		err := cm.Action(src, dst)
+		err = cm.Action(src, dst)
+		if err == errCtrlC {
+			fmt.Fprintln(cm.Err(), "^C")
+			return 0, nil
+		}
 		if err != nil {
 			if i >= len(srcs)-1 {
 				return 1, err
@@ -0,0 +1,337 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/nyaosorg/nyagos/internal/nodos"
+)
+
+// files larger than this are copied via `DST.part` to resume later.
+// The smaller ones are copied by nodos.Copy.
+var copyResumeSize int64 = 4 * 1024 * 1024
+
+const copyPartSuffix = ".part"
+
+// copyJob is one file or folder copied by treeCopier
+type copyJob struct {
+	src  string
+	dst  string
+	info os.FileInfo
+}
+
+// treeCopier copies files and folders for `copy`.
+type treeCopier struct {
+	recursive bool
+	update    bool
+	dryRun    bool
+	log       io.Writer
+	progress  *copyProgress
+}
+
+func newTreeCopier(cmd Param) *treeCopier {
+	cp := &treeCopier{log: cmd.Err()}
+	if f, ok := cmd.Term().(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+		cp.progress = &copyProgress{out: f}
+	}
+	return cp
+}
+
+// isUpToDate returns true when dst is not older than src.
+func isUpToDate(src os.FileInfo, dst string) bool {
+	dstStat, err := os.Stat(dst)
+	return err == nil && !src.ModTime().After(dstStat.ModTime())
+}
+
+// plan lists the files to copy from src to dst.
+// Folders come before their contents.
+// The symbolic links are copied as links only in the folders copied with /s,
+// otherwise the files they point to are copied.
+func (cp *treeCopier) plan(src, dst string, jobs []copyJob, inTree bool) ([]copyJob, error) {
+	stat, err := os.Stat(src)
+	if inTree {
+		stat, err = os.Lstat(src)
+	}
+	if err != nil {
+		return jobs, err
+	}
+	if !stat.IsDir() {
+		if cp.update && isUpToDate(stat, dst) {
+			return jobs, nil
+		}
+		return append(jobs, copyJob{src: src, dst: dst, info: stat}), nil
+	}
+	if !cp.recursive {
+		return jobs, fmt.Errorf("%s is directory and passed", src)
+	}
+	jobs = append(jobs, copyJob{src: src, dst: dst, info: stat})
+	files, err := os.ReadDir(src)
+	if err != nil {
+		return jobs, err
+	}
+	for _, f := range files {
+		jobs, err = cp.plan(filepath.Join(src, f.Name()), filepath.Join(dst, f.Name()), jobs, true)
+		if err != nil {
+			return jobs, err
+		}
+	}
+	return jobs, nil
+}
+
+// keepAttributes copies the timestamp and the attributes of the source.
+func keepAttributes(job *copyJob) error {
+	modTime := job.info.ModTime()
+	if err := os.Chtimes(job.dst, modTime, modTime); err != nil {
+		return err
+	}
+	if attr, err := nodos.GetFileAttributes(job.src); err == nil {
+		return nodos.SetFileAttributes(job.dst, attr)
+	}
+	return nil
+}
+
+// Copy copies src to dst. Folders are copied with the contents when /s is given.
+func (cp *treeCopier) Copy(ctx context.Context, src, dst string) error {
+	jobs, err := cp.plan(src, dst, nil, false)
+	if err != nil {
+		return err
+	}
+	if cp.dryRun {
+		for _, job := range jobs {
+			if !job.info.IsDir() {
+				fmt.Fprintf(cp.log, "(dry-run) %s -> %s\n", job.src, job.dst)
+			}
+		}
+		return nil
+	}
+	var total int64
+	for _, job := range jobs {
+		if job.info.Mode().IsRegular() {
+			total += job.info.Size()
+		}
+	}
+	if cp.progress != nil {
+		cp.progress.start(total)
+		defer cp.progress.clear()
+	}
+	folders := []*copyJob{}
+	for i := range jobs {
+		if ctx != nil {
+			select {
+			case <-ctx.Done():
+				return errCtrlC
+			default:
+			}
+		}
+		job := &jobs[i]
+		if job.info.IsDir() {
+			if err := os.MkdirAll(job.dst, 0777); err != nil {
+				return err
+			}
+			folders = append(folders, job)
+			continue
+		}
+		if i > 0 {
+			cp.progress.clear()
+			fmt.Fprintf(cp.log, "%s -> %s\n", job.src, job.dst)
+		}
+		if job.info.Mode()&os.ModeSymlink != 0 {
+			if err := copySymlink(job.src, job.dst); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := cp.copyFile(ctx, job); err != nil {
+			return err
+		}
+		if err := keepAttributes(job); err != nil {
+			return err
+		}
+	}
+	// The timestamps of folders are changed while the contents are copied.
+	for i := len(folders) - 1; i >= 0; i-- {
+		if err := keepAttributes(folders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copySymlink(src, dst string) error {
+	linkTo, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	os.Remove(dst)
+	return os.Symlink(linkTo, dst)
+}
+
+// resumePoint returns the size of the partial file which has
+// the same tail as the source, or 0 when it can not be resumed.
+func resumePoint(src *os.File, part string, size int64) int64 {
+	stat, err := os.Stat(part)
+	if err != nil || stat.Size() <= 0 || stat.Size() >= size {
+		return 0
+	}
+	partSize := stat.Size()
+	tailSize := int64(64 * 1024)
+	if tailSize > partSize {
+		tailSize = partSize
+	}
+	partFd, err := os.Open(part)
+	if err != nil {
+		return 0
+	}
+	defer partFd.Close()
+	tail1 := make([]byte, tailSize)
+	tail2 := make([]byte, tailSize)
+	if _, err := partFd.ReadAt(tail1, partSize-tailSize); err != nil {
+		return 0
+	}
+	if _, err := src.ReadAt(tail2, partSize-tailSize); err != nil {
+		return 0
+	}
+	if !bytes.Equal(tail1, tail2) {
+		return 0
+	}
+	return partSize
+}
+
+func (cp *treeCopier) copyFile(ctx context.Context, job *copyJob) error {
+	size := job.info.Size()
+	if size < copyResumeSize {
+		if err := nodos.Copy(job.src, job.dst, false); err != nil {
+			return err
+		}
+		cp.progress.add(size)
+		return nil
+	}
+
+	srcFd, err := os.Open(job.src)
+	if err != nil {
+		return err
+	}
+	defer srcFd.Close()
+
+	// The large file is written to DST.part and renamed after completed,
+	// so the copy canceled by Ctrl-C can be resumed.
+	part := job.dst + copyPartSuffix
+	var dstFd *os.File
+	if offset := resumePoint(srcFd, part, size); offset > 0 {
+		cp.progress.clear()
+		fmt.Fprintf(cp.log, "%s: resume from %s\n", job.dst, formatByHumanize(offset))
+		if _, err := srcFd.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		dstFd, err = os.OpenFile(part, os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+		cp.progress.add(offset)
+	} else {
+		dstFd, err = os.Create(part)
+		if err != nil {
+			return err
+		}
+	}
+	err = cp.copyBody(ctx, dstFd, srcFd)
+	if err1 := dstFd.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(part, job.dst)
+}
+
+func (cp *treeCopier) copyBody(ctx context.Context, w io.Writer, r io.Reader) error {
+	buffer := make([]byte, 1024*1024)
+	for {
+		if ctx != nil {
+			select {
+			case <-ctx.Done():
+				return errCtrlC
+			default:
+			}
+		}
+		n, err := r.Read(buffer)
+		if n > 0 {
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return err
+			}
+			cp.progress.add(int64(n))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// copyProgress draws the progress bar with bytes and ETA on the terminal.
+type copyProgress struct {
+	out      io.Writer
+	total    int64
+	done     int64
+	startAt  time.Time
+	lastDraw time.Time
+	width    int
+}
+
+func (p *copyProgress) start(total int64) {
+	p.total = total
+	p.done = 0
+	p.startAt = time.Now()
+	p.lastDraw = p.startAt
+}
+
+func (p *copyProgress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.done += n
+	if now := time.Now(); now.Sub(p.lastDraw) >= 200*time.Millisecond {
+		p.lastDraw = now
+		p.draw(now)
+	}
+}
+
+func (p *copyProgress) draw(now time.Time) {
+	if p.total <= 0 {
+		return
+	}
+	const barWidth = 20
+	filled := int(p.done * barWidth / p.total)
+	eta := "--:--"
+	if elapsed := now.Sub(p.startAt); p.done > 0 && elapsed > time.Second {
+		rest := time.Duration(float64(elapsed) * float64(p.total-p.done) / float64(p.done))
+		eta = fmt.Sprintf("%02d:%02d", int(rest.Minutes()), int(rest.Seconds())%60)
+	}
+	line := fmt.Sprintf("[%s%s] %3d%% %s/%s ETA %s",
+		strings.Repeat("#", filled),
+		strings.Repeat("-", barWidth-filled),
+		p.done*100/p.total,
+		formatByHumanize(p.done),
+		formatByHumanize(p.total),
+		eta)
+	fmt.Fprintf(p.out, "\r%s\x1B[K", line)
+	p.width = len(line)
+}
+
+func (p *copyProgress) clear() {
+	if p == nil || p.width <= 0 {
+		return
+	}
+	io.WriteString(p.out, "\r\x1B[K")
+	p.width = 0
+}
@@ -0,0 +1,98 @@
+package commands_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+)
+
+func TestCopyTree(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0777); err != nil {
+		t.Fatal(err.Error())
+	}
+	file := filepath.Join(src, "sub", "a.txt")
+	if err := os.WriteFile(file, []byte("old"), 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	stamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(file, stamp, stamp); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testExec(t, "copy", "/y", "--dry-run", "/s", src, dst)
+	if _, err := os.Stat(dst); err == nil {
+		t.Fatal("copy --dry-run: the destination is created")
+	}
+
+	testExec(t, "copy", "/y", "/s", src, dst)
+	copied := filepath.Join(dst, "sub", "a.txt")
+	stat, err := os.Stat(copied)
+	if err != nil {
+		t.Fatalf("copy /s: %s", err.Error())
+	}
+	if !stat.ModTime().Equal(stamp) {
+		t.Fatalf("copy /s: timestamp %v is not kept", stat.ModTime())
+	}
+
+	// --update does not overwrite the newer file
+	if err := os.WriteFile(copied, []byte("new"), 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	testExec(t, "copy", "/y", "/s", "--update", src, dst)
+	if data, _ := os.ReadFile(copied); string(data) != "new" {
+		t.Fatalf("copy --update: %#v", string(data))
+	}
+}
+
+func TestCopyResume(t *testing.T) {
+	defer func(size int64) { *commands.CopyResumeSize = size }(*commands.CopyResumeSize)
+	*commands.CopyResumeSize = 1024
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	dst := filepath.Join(dir, "dst.bin")
+	data := make([]byte, 200*1024)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	if err := os.WriteFile(src, data, 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Only the tail of the partial file is compared with the source,
+	// so the changed head is kept when the copy is resumed.
+	part := append([]byte{}, data[:150*1024]...)
+	part[0] ^= 0xFF
+	if err := os.WriteFile(dst+".part", part, 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	testExec(t, "copy", "/y", src, dst)
+	result, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if result[0] != part[0] || !bytes.Equal(result[1:], data[1:]) {
+		t.Fatal("copy: not resumed from the partial file")
+	}
+	if _, err := os.Stat(dst + ".part"); err == nil {
+		t.Fatal("copy: the partial file is left")
+	}
+
+	// The partial file whose tail differs is copied again from the top.
+	part = append([]byte{}, data[:150*1024]...)
+	part[len(part)-1] ^= 0xFF
+	if err := os.WriteFile(dst+".part", part, 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	testExec(t, "copy", "/y", src, dst)
+	if result, err := os.ReadFile(dst); err != nil || !bytes.Equal(result, data) {
+		t.Fatalf("copy: not copied again (%v)", err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopySymlink(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	src := filepath.Join(dir, "src")
+	if err := os.Mkdir(src, 0777); err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, link := range []string{filepath.Join(dir, "link.txt"), filepath.Join(src, "link.txt")} {
+		if err := os.Symlink(filepath.Join(dir, "a.txt"), link); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	out := filepath.Join(dir, "out.txt")
+	testExec(t, "copy", "/y", filepath.Join(dir, "link.txt"), out)
+	if stat, err := os.Lstat(out); err != nil || !stat.Mode().IsRegular() {
+		t.Fatalf("copy link.txt out.txt: %v %v", stat, err)
+	}
+
+	testExec(t, "copy", "/y", "/s", src, filepath.Join(dir, "dst"))
+	if stat, err := os.Lstat(filepath.Join(dir, "dst", "link.txt")); err != nil || stat.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("copy /s: the link in the tree is not kept: %v %v", stat, err)
+	}
+}
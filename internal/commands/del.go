@@ -7,17 +7,21 @@ import (
 	"unicode"
 
 	"syscall"
+
+	"github.com/nyaosorg/nyagos/internal/nodos"
 )
 
 func cmdDel(ctx context.Context, cmd Param) (int, error) {
 	n := len(cmd.Args())
 	if n <= 1 {
-		fmt.Fprintln(cmd.Err(), "Usage: del   [/q] FILE(S)...")
-		fmt.Fprintln(cmd.Err(), "       erase [/q] FILE(S)...")
+		fmt.Fprintln(cmd.Err(), "Usage: del   [/q] [--trash] FILE(S)...")
+		fmt.Fprintln(cmd.Err(), "       erase [/q] [--trash] FILE(S)...")
 		return 0, nil
 	}
 	all := false
 	force := false
+	trash := false
+	action := "Remove"
 	errorcount := 0
 	i := 1
 	for _, arg1 := range cmd.Args()[1:] {
@@ -38,6 +42,12 @@ func cmdDel(ctx context.Context, cmd Param) (int, error) {
 			n--
 			continue
 		}
+		if arg1 == "--trash" {
+			trash = true
+			action = "Move to trash"
+			n--
+			continue
+		}
 		path := arg1
 		stat, err := os.Lstat(path)
 		if _, ok := err.(*os.PathError); ok || os.IsNotExist(err) {
@@ -57,11 +67,11 @@ func cmdDel(ctx context.Context, cmd Param) (int, error) {
 			continue
 		}
 		if all {
-			fmt.Fprintf(cmd.Out(), "(%d/%d) %s: Remove ", i, n-1, path)
+			fmt.Fprintf(cmd.Out(), "(%d/%d) %s: %s ", i, n-1, path, action)
 		} else {
 			fmt.Fprintf(cmd.Out(),
-				"(%d/%d) %s: Remove ? [Yes/No/All/Quit] ",
-				i, n-1, path)
+				"(%d/%d) %s: %s ? [Yes/No/All/Quit] ",
+				i, n-1, path, action)
 			ch, err := getkey()
 			if err != nil {
 				return 1, err
@@ -82,8 +92,12 @@ func cmdDel(ctx context.Context, cmd Param) (int, error) {
 				continue
 			}
 		}
-		err = syscall.Unlink(path)
-		if err != nil && force {
+		if trash {
+			err = nodos.MoveToTrash(path)
+		} else {
+			err = syscall.Unlink(path)
+		}
+		if err != nil && force && !trash {
 			if err1 := setWritable(path); err1 == nil {
 				err = syscall.Unlink(path)
 			}
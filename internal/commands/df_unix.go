@@ -11,3 +11,7 @@ import (
 func cmdDiskFree(_ context.Context, cmd Param) (int, error) {
 	return 1, errors.New("not supported")
 }
+
+func dfRecords(_ context.Context, cmd Param) ([]Record, error) {
+	return nil, errors.New("not supported")
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"strconv"
 
 	"golang.org/x/sys/windows"
@@ -35,7 +36,18 @@ func driveType(rootPathName string) (uint32, string) {
 	}
 }
 
-func df(rootPathName string) ([]string, error) {
+type diskFreeT struct {
+	root      string
+	free      uint64
+	total     uint64
+	totalFree uint64
+	fs        string
+	driveType string
+	label     string
+	uncPath   string
+}
+
+func df(rootPathName string) (*diskFreeT, error) {
 	label, fs, err := netresource.VolumeName(rootPathName)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s", rootPathName, err)
@@ -49,42 +61,59 @@ func df(rootPathName string) ([]string, error) {
 	if driveTypeID == windows.DRIVE_REMOTE {
 		uncPath, _ = netresource.WNetGetConnectionUTF16a(uint16(rootPathName[0]))
 	}
+	return &diskFreeT{
+		root:      rootPathName,
+		free:      free,
+		total:     total,
+		totalFree: totalFree,
+		fs:        fs,
+		driveType: driveTypeStr,
+		label:     label,
+		uncPath:   uncPath,
+	}, nil
+}
+
+func (d *diskFreeT) usePercent() uint64 {
+	if d.total == 0 {
+		return 0
+	}
+	return 100 * (d.total - d.free) / d.total
+}
 
+func (d *diskFreeT) strings() []string {
 	return []string{
-		rootPathName,
-		humanize.Comma(int64(free)),
-		humanize.Comma(int64(total)),
-		humanize.Comma(int64(totalFree)),
-		strconv.FormatUint(100*(total-free)/total, 10),
-		fs,
-		driveTypeStr,
-		label,
-		uncPath,
-	}, nil
+		d.root,
+		humanize.Comma(int64(d.free)),
+		humanize.Comma(int64(d.total)),
+		humanize.Comma(int64(d.totalFree)),
+		strconv.FormatUint(d.usePercent(), 10),
+		d.fs,
+		d.driveType,
+		d.label,
+		d.uncPath,
+	}
 }
 
-func cmdDiskFree(_ context.Context, cmd Param) (int, error) {
+// diskFree returns the status of the drives in args or all drives.
+func diskFree(args []string, stderr io.Writer) ([]*diskFreeT, error) {
 	bits, err := windows.GetLogicalDrives()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	dfs := [][]string{
-		[]string{"", "Available", "Total", "TotalFree", "Use%"},
-	}
-
-	for _, arg1 := range cmd.Args()[1:] {
+	dfs := []*diskFreeT{}
+	for _, arg1 := range args {
 		if df1, err := df(arg1); err != nil {
-			fmt.Fprintln(cmd.Err(), err)
+			fmt.Fprintln(stderr, err)
 		} else {
 			dfs = append(dfs, df1)
 		}
 	}
-	if len(dfs) <= 1 {
+	if len(dfs) <= 0 {
 		for d := 'A'; d <= 'Z'; d++ {
 			if (bits & 1) != 0 {
 				rootPathName := fmt.Sprintf("%c:\\", d)
 				if df1, err := df(rootPathName); err != nil {
-					fmt.Fprintln(cmd.Err(), err)
+					fmt.Fprintln(stderr, err)
 				} else {
 					dfs = append(dfs, df1)
 				}
@@ -92,6 +121,42 @@ func cmdDiskFree(_ context.Context, cmd Param) (int, error) {
 			bits >>= 1
 		}
 	}
+	return dfs, nil
+}
+
+func dfRecords(_ context.Context, cmd Param) ([]Record, error) {
+	drives, err := diskFree(cmd.Args()[1:], cmd.Err())
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(drives))
+	for _, d := range drives {
+		records = append(records, Record{
+			{"drive", d.root},
+			{"available", d.free},
+			{"total", d.total},
+			{"totalfree", d.totalFree},
+			{"use", d.usePercent()},
+			{"filesystem", d.fs},
+			{"type", d.driveType},
+			{"label", d.label},
+			{"unc", d.uncPath},
+		})
+	}
+	return records, nil
+}
+
+func cmdDiskFree(_ context.Context, cmd Param) (int, error) {
+	drives, err := diskFree(cmd.Args()[1:], cmd.Err())
+	if err != nil {
+		return 0, err
+	}
+	dfs := [][]string{
+		[]string{"", "Available", "Total", "TotalFree", "Use%"},
+	}
+	for _, d := range drives {
+		dfs = append(dfs, d.strings())
+	}
 
 	colsiz := []int{}
 	for _, df1 := range dfs {
@@ -1,12 +1,18 @@
 package commands
 
 import (
+	"container/heap"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 var errCtrlC = errors.New("^C")
@@ -15,100 +21,335 @@ func printDu1Line(out io.Writer, name string, size int64) {
 	fmt.Fprintf(out, "%7s %s\n", formatByHumanize(size), name)
 }
 
-// _du returns the sum (bytes) of path
-func _du(path string, output func(string, int64) error, stderr io.Writer, blocksize int64) (int64, error) {
-	fd, err := os.Open(path)
-	if err != nil {
-		return 0, err
+type duOptions struct {
+	maxDepth int // -1: no limit
+	top      int // 0: all
+	excludes []string
+	apparent bool
+	oneFS    bool
+	// interactive keeps the files in the tree for the browser of -i
+	interactive bool
+}
+
+const duUsage = "Usage: diskused [-i] [-s] [-b] [-x] [-d DEPTH] [-n TOP] [--exclude=PATTERN] [PATH(s)...]"
+
+// parseDuOptions returns the options and the paths given by args
+// which do not include the command name.
+func parseDuOptions(args []string) (*duOptions, []string, error) {
+	opt := &duOptions{maxDepth: -1}
+	paths := []string{}
+	number := func(name, value string) (int, error) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("diskused: %s: invalid number: %s", name, value)
+		}
+		return n, nil
 	}
-	stat, err := fd.Stat()
-	if err != nil {
-		return 0, err
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		key, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			if pos := strings.IndexByte(arg, '='); pos >= 0 {
+				key, value, hasValue = arg[:pos], arg[pos+1:], true
+			}
+		}
+		switch key {
+		case "-s", "--summarize":
+			opt.maxDepth = 0
+			continue
+		case "-b", "--apparent-size":
+			opt.apparent = true
+			continue
+		case "-x", "--one-file-system":
+			opt.oneFS = true
+			continue
+		case "-i", "--interactive":
+			opt.interactive = true
+			continue
+		case "-d", "--max-depth", "-n", "--top", "--exclude":
+		default:
+			if strings.HasPrefix(arg, "-") && arg != "-" {
+				return nil, nil, errors.New(duUsage)
+			}
+			paths = append(paths, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("diskused: %s: value missing", key)
+			}
+			i++
+			value = args[i]
+		}
+		var err error
+		switch key {
+		case "-d", "--max-depth":
+			opt.maxDepth, err = number(key, value)
+		case "-n", "--top":
+			opt.top, err = number(key, value)
+		default:
+			opt.excludes = append(opt.excludes, value)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	if !stat.IsDir() {
-		fd.Close()
-		return ((stat.Size() + blocksize - 1) / blocksize) * blocksize, nil
+	return opt, paths, nil
+}
+
+// duNode is a folder walked by duWalker
+type duNode struct {
+	path     string
+	depth    int
+	size     int64
+	parent   *duNode
+	children []*duNode
+	files    []duEntry // only with -i
+}
+
+// duEntry is a file or a folder ranked by the size
+type duEntry struct {
+	path string
+	size int64
+}
+
+// duRanking keeps the largest N entries as a min-heap.
+type duRanking []duEntry
+
+func (r duRanking) Len() int            { return len(r) }
+func (r duRanking) Less(i, j int) bool  { return r[i].size < r[j].size }
+func (r duRanking) Swap(i, j int)       { r[i], r[j] = r[j], r[i] }
+func (r *duRanking) Push(x interface{}) { *r = append(*r, x.(duEntry)) }
+func (r *duRanking) Pop() interface{} {
+	old := *r
+	x := old[len(old)-1]
+	*r = old[:len(old)-1]
+	return x
+}
+
+// duWalker sums the size of folders with a bounded pool of goroutines.
+type duWalker struct {
+	ctx    context.Context
+	opt    *duOptions
+	stderr io.Writer
+	sem    chan struct{}
+
+	mu      sync.Mutex
+	ranking duRanking
+}
+
+func newDuWalker(ctx context.Context, opt *duOptions, stderr io.Writer) *duWalker {
+	workers := runtime.NumCPU() * 4
+	if workers < 8 {
+		workers = 8
 	}
-	files, err := fd.Readdir(-1)
-	if err != nil {
-		fd.Close()
-		return 0, err
+	return &duWalker{
+		ctx:    ctx,
+		opt:    opt,
+		stderr: stderr,
+		sem:    make(chan struct{}, workers),
 	}
-	var diskuse int64
-	dirs := make([]string, 0, len(files))
-	for _, file1 := range files {
-		if file1.IsDir() {
-			dirs = append(dirs, file1.Name())
-		} else {
-			diskuse += ((file1.Size() + blocksize - 1) / blocksize) * blocksize
-		}
+}
+
+func (w *duWalker) canceled() bool {
+	if w.ctx == nil {
+		return false
 	}
-	if err := fd.Close(); err != nil {
-		return diskuse, err
+	select {
+	case <-w.ctx.Done():
+		return true
+	default:
+		return false
 	}
-	for _, dir1 := range dirs {
-		fullpath := filepath.Join(path, dir1)
-		diskuse1, err := _du(fullpath, output, stderr, blocksize)
-		if err == errCtrlC {
-			return diskuse, err
-		}
-		if err == nil {
-			if err = output(fullpath, diskuse1); err == nil {
-				diskuse += diskuse1
-				continue
-			} else if err == errCtrlC {
-				return diskuse1, errCtrlC
-			}
+}
+
+func (w *duWalker) warn(path string, err error) {
+	w.mu.Lock()
+	fmt.Fprintf(w.stderr, "%s: %s\n", path, err)
+	w.mu.Unlock()
+}
+
+func (w *duWalker) excluded(name, path string) bool {
+	for _, pattern := range w.opt.excludes {
+		if m, _ := filepath.Match(pattern, name); m {
+			return true
+		}
+		if m, _ := filepath.Match(pattern, path); m {
+			return true
 		}
-		fmt.Fprintf(stderr, "%s: %s\n", fullpath, err)
 	}
-	return diskuse, nil
+	return false
 }
 
-func cmdDiskUsed(ctx context.Context, cmd Param) (int, error) {
-	output := func(name string, size int64) error {
-		printDu1Line(cmd.Out(), name, size)
-		if ctx != nil {
-			select {
-			case <-ctx.Done():
-				return errCtrlC
-			default:
-			}
+func (w *duWalker) sizeOf(f os.FileInfo) int64 {
+	if w.opt.apparent {
+		return f.Size()
+	}
+	return duAllocated(f)
+}
+
+// rank records the entry for -n when it is not deeper than -d
+func (w *duWalker) rank(path string, size int64, depth int) {
+	if w.opt.top <= 0 || (w.opt.maxDepth >= 0 && depth > w.opt.maxDepth) {
+		return
+	}
+	w.mu.Lock()
+	if len(w.ranking) < w.opt.top {
+		heap.Push(&w.ranking, duEntry{path: path, size: size})
+	} else if w.ranking[0].size < size {
+		w.ranking[0] = duEntry{path: path, size: size}
+		heap.Fix(&w.ranking, 0)
+	}
+	w.mu.Unlock()
+}
+
+// walk sums the size of the folder. Sub-folders are walked on other
+// goroutines while the pool has room, otherwise on the current one.
+func (w *duWalker) walk(node *duNode, device uint64) {
+	if w.canceled() {
+		return
+	}
+	fd, err := os.Open(node.path)
+	if err != nil {
+		w.warn(node.path, err)
+		return
+	}
+	files, err := fd.Readdir(-1)
+	fd.Close()
+	if err != nil {
+		w.warn(node.path, err)
+	}
+	var wg sync.WaitGroup
+	for _, f := range files {
+		path := filepath.Join(node.path, f.Name())
+		if w.excluded(f.Name(), path) {
+			continue
 		}
-		return nil
-	}
-	count := 0
-	for _, arg1 := range cmd.Args()[1:] {
-		if arg1 == "-s" {
-			output = func(_ string, _ int64) error {
-				if ctx != nil {
-					select {
-					case <-ctx.Done():
-						return errCtrlC
-					default:
-					}
-				}
-				return nil
+		if !f.IsDir() {
+			size := w.sizeOf(f)
+			node.size += size
+			if w.opt.interactive {
+				node.files = append(node.files, duEntry{path: path, size: size})
 			}
+			w.rank(path, size, node.depth+1)
 			continue
 		}
-		size, err := _du(arg1, output, cmd.Err(), 4096)
-		count++
-		if err != nil {
-			fmt.Fprintf(cmd.Err(), "%s: %s\n", arg1, err)
-			if err == errCtrlC {
-				return 0, err
-			}
+		if w.opt.oneFS && duDevice(f) != device {
 			continue
 		}
-		printDu1Line(cmd.Out(), arg1, size)
+		child := &duNode{path: path, depth: node.depth + 1, parent: node}
+		node.children = append(node.children, child)
+		select {
+		case w.sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				w.walk(child, device)
+				<-w.sem
+				wg.Done()
+			}()
+		default:
+			w.walk(child, device)
+		}
+	}
+	wg.Wait()
+	for _, child := range node.children {
+		node.size += child.size
+	}
+	w.rank(node.path, node.size, node.depth)
+}
+
+// scan returns the tree of folders under path.
+func (w *duWalker) scan(path string) (*duNode, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
 	}
-	if count <= 0 {
-		size, err := _du(".", output, cmd.Err(), 4096)
+	node := &duNode{path: path}
+	if !stat.IsDir() {
+		node.size = w.sizeOf(stat)
+		w.rank(path, node.size, 0)
+		return node, nil
+	}
+	w.walk(node, duDevice(stat))
+	if w.canceled() {
+		return node, errCtrlC
+	}
+	return node, nil
+}
+
+// report calls f with the folders not deeper than -d, children first.
+func (node *duNode) report(maxDepth int, f func(string, int64)) {
+	if maxDepth < 0 || node.depth < maxDepth {
+		for _, child := range node.children {
+			child.report(maxDepth, f)
+		}
+	}
+	f(node.path, node.size)
+}
+
+// diskUsed calls report with the size of each folder and each path,
+// or with the largest entries when -n is given.
+func diskUsed(ctx context.Context, opt *duOptions, paths []string, stderr io.Writer, report func(string, int64)) error {
+	w := newDuWalker(ctx, opt, stderr)
+	for _, path := range paths {
+		node, err := w.scan(path)
+		if err == errCtrlC {
+			return err
+		}
 		if err != nil {
-			return 1, err
+			fmt.Fprintf(stderr, "%s: %s\n", path, err)
+			continue
+		}
+		if opt.top <= 0 {
+			node.report(opt.maxDepth, report)
 		}
-		printDu1Line(cmd.Out(), ".", size)
+	}
+	if opt.top > 0 {
+		sort.Slice(w.ranking, func(i, j int) bool {
+			return w.ranking[i].size > w.ranking[j].size
+		})
+		for _, e := range w.ranking {
+			report(e.path, e.size)
+		}
+	}
+	return nil
+}
+
+func cmdDiskUsed(ctx context.Context, cmd Param) (int, error) {
+	opt, paths, err := parseDuOptions(cmd.Args()[1:])
+	if err != nil {
+		return 1, err
+	}
+	if len(paths) <= 0 {
+		paths = []string{"."}
+	}
+	if opt.interactive {
+		err = browseDiskUsed(ctx, opt, paths[0], cmd.Err())
+	} else {
+		err = diskUsed(ctx, opt, paths, cmd.Err(), func(name string, size int64) {
+			printDu1Line(cmd.Out(), name, size)
+		})
+	}
+	if err == errCtrlC {
+		return 0, err
+	}
+	if err != nil {
+		return 1, err
 	}
 	return 0, nil
 }
+
+func duRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	opt, paths, err := parseDuOptions(cmd.Args()[1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) <= 0 {
+		paths = []string{"."}
+	}
+	records := []Record{}
+	err = diskUsed(ctx, opt, paths, cmd.Err(), func(name string, size int64) {
+		records = append(records, Record{{"path", name}, {"size", size}})
+	})
+	return records, err
+}
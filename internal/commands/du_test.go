@@ -0,0 +1,43 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiskUsed(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"a.txt", "sub/b.txt", "sub/deep/c.dat", "other/d.txt"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", 100*(i+1))), 0666); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	sizes := func(args ...string) map[string]int64 {
+		t.Helper()
+		result := map[string]int64{}
+		for _, r := range testRecords(t, append([]string{"diskused"}, args...)...) {
+			m := r.Map()
+			rel, _ := filepath.Rel(dir, m["path"].(string))
+			result[filepath.ToSlash(rel)] = m["size"].(int64)
+		}
+		return result
+	}
+	if s := sizes("-b", dir); s["."] != 1000 || s["sub"] != 500 || s["sub/deep"] != 300 || len(s) != 4 {
+		t.Fatalf("diskused -b: %v", s)
+	}
+	if s := sizes("-b", "-d", "1", dir); len(s) != 3 || s["other"] != 400 {
+		t.Fatalf("diskused -b -d 1: %v", s)
+	}
+	if s := sizes("-b", "--exclude=*.dat", "-s", dir); len(s) != 1 || s["."] != 700 {
+		t.Fatalf("diskused --exclude: %v", s)
+	}
+	if s := sizes("-b", "-n", "2", dir); len(s) != 2 || s["."] != 1000 || s["sub"] != 500 {
+		t.Fatalf("diskused -n 2: %v", s)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"os"
+	"syscall"
+)
+
+// duAllocated returns the size of the blocks allocated for the file.
+func duAllocated(f os.FileInfo) int64 {
+	if st, ok := f.Sys().(*syscall.Stat_t); ok {
+		return int64(st.Blocks) * 512
+	}
+	return f.Size()
+}
+
+// duDevice returns the device which the file is on.
+func duDevice(f os.FileInfo) uint64 {
+	if st, ok := f.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)
+	}
+	return 0
+}
@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"os"
+)
+
+const duClusterSize = 4096
+
+// duAllocated returns the size rounded up by the cluster.
+func duAllocated(f os.FileInfo) int64 {
+	return ((f.Size() + duClusterSize - 1) / duClusterSize) * duClusterSize
+}
+
+// duDevice returns 0 because the other volumes are mounted on
+// the junctions which diskused does not follow.
+func duDevice(f os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,292 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-tty"
+
+	"github.com/nyaosorg/nyagos/internal/textwidth"
+)
+
+// duItem is a row of the browser of `diskused -i`
+type duItem struct {
+	name string
+	size int64
+	dir  *duNode // nil for files
+}
+
+// items returns the folders and the files in the node sorted by the size
+func (node *duNode) items() []duItem {
+	items := make([]duItem, 0, len(node.children)+len(node.files))
+	for _, child := range node.children {
+		items = append(items, duItem{name: filepath.Base(child.path), size: child.size, dir: child})
+	}
+	for _, f := range node.files {
+		items = append(items, duItem{name: filepath.Base(f.path), size: f.size})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].size != items[j].size {
+			return items[i].size > items[j].size
+		}
+		return items[i].name < items[j].name
+	})
+	return items
+}
+
+// remove deletes the item from the tree and subtracts the size from the ancestors.
+func (node *duNode) remove(item duItem) {
+	if item.dir != nil {
+		for i, child := range node.children {
+			if child == item.dir {
+				node.children = append(node.children[:i], node.children[i+1:]...)
+				break
+			}
+		}
+	} else {
+		for i, f := range node.files {
+			if filepath.Base(f.path) == item.name {
+				node.files = append(node.files[:i], node.files[i+1:]...)
+				break
+			}
+		}
+	}
+	for p := node; p != nil; p = p.parent {
+		p.size -= item.size
+	}
+}
+
+// cutWidth cuts s to fit in width cells of the terminal.
+func cutWidth(s string, width int) string {
+	w := 0
+	for i, c := range s {
+		w1 := textwidth.RuneWidth(c)
+		if w+w1 > width {
+			return s[:i]
+		}
+		w += w1
+	}
+	return s
+}
+
+type duBrowser struct {
+	tty    *tty.TTY
+	out    io.Writer
+	node   *duNode
+	items  []duItem
+	cursor int
+	top    int
+	// cursors keeps the position in the parent folders
+	cursors []int
+	message string
+}
+
+func (b *duBrowser) reload() {
+	b.items = b.node.items()
+	if b.cursor >= len(b.items) {
+		b.cursor = len(b.items) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+}
+
+func (b *duBrowser) draw() {
+	width, height, err := b.tty.Size()
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 25
+	}
+	rows := height - 2
+	if rows < 1 {
+		rows = 1
+	}
+	if b.cursor < b.top {
+		b.top = b.cursor
+	} else if b.cursor >= b.top+rows {
+		b.top = b.cursor - rows + 1
+	}
+
+	var buffer strings.Builder
+	buffer.WriteString("\x1B[H\x1B[1m")
+	buffer.WriteString(cutWidth(fmt.Sprintf("%7s %s", formatByHumanize(b.node.size), b.node.path), width-1))
+	buffer.WriteString("\x1B[0m\x1B[K\r\n")
+
+	barWidth := 20
+	if width < 60 {
+		barWidth = 10
+	}
+	var largest int64 = 1
+	if len(b.items) > 0 && b.items[0].size > 0 {
+		largest = b.items[0].size
+	}
+	for i := b.top; i < b.top+rows; i++ {
+		if i >= len(b.items) {
+			buffer.WriteString("\x1B[K\r\n")
+			continue
+		}
+		item := &b.items[i]
+		filled := int(item.size * int64(barWidth) / largest)
+		name := item.name
+		if item.dir != nil {
+			name += string(os.PathSeparator)
+		}
+		line := fmt.Sprintf("%7s [%s%s] %s",
+			formatByHumanize(item.size),
+			strings.Repeat("#", filled),
+			strings.Repeat(" ", barWidth-filled),
+			name)
+		if i == b.cursor {
+			buffer.WriteString("\x1B[7m")
+		}
+		buffer.WriteString(cutWidth(line, width-1))
+		buffer.WriteString("\x1B[0m\x1B[K\r\n")
+	}
+	message := b.message
+	if message == "" {
+		message = "Up/Down:move Right/Enter:open Left:parent d:delete q:quit"
+	}
+	buffer.WriteString("\x1B[7m")
+	buffer.WriteString(cutWidth(message, width-1))
+	buffer.WriteString("\x1B[0m\x1B[K")
+	io.WriteString(b.out, buffer.String())
+}
+
+// getKey reads a key and the following escape sequence.
+func (b *duBrowser) getKey() (string, error) {
+	return readKey(b.tty)
+}
+
+func (b *duBrowser) enter() {
+	if b.cursor >= len(b.items) || b.items[b.cursor].dir == nil {
+		return
+	}
+	b.cursors = append(b.cursors, b.cursor)
+	b.node = b.items[b.cursor].dir
+	b.cursor = 0
+	b.top = 0
+	b.reload()
+}
+
+func (b *duBrowser) leave() {
+	if b.node.parent == nil {
+		return
+	}
+	b.node = b.node.parent
+	b.cursor = b.cursors[len(b.cursors)-1]
+	b.cursors = b.cursors[:len(b.cursors)-1]
+	b.reload()
+}
+
+func (b *duBrowser) delete() error {
+	if b.cursor >= len(b.items) {
+		return nil
+	}
+	item := b.items[b.cursor]
+	path := filepath.Join(b.node.path, item.name)
+	b.message = fmt.Sprintf("Delete %s (%s) ? [y/N]", path, formatByHumanize(item.size))
+	b.draw()
+	b.message = ""
+	key, err := b.getKey()
+	if err != nil {
+		return err
+	}
+	if key != "y" && key != "Y" {
+		return nil
+	}
+	if item.dir != nil {
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		b.message = err.Error()
+		return nil
+	}
+	b.node.remove(item)
+	b.reload()
+	return nil
+}
+
+// browseDiskUsed scans the folder once and browses the size tree on the terminal.
+func browseDiskUsed(ctx context.Context, opt *duOptions, path string, stderr io.Writer) error {
+	fmt.Fprintf(stderr, "Scanning %s ...", path)
+	w := newDuWalker(ctx, opt, stderr)
+	root, err := w.scan(path)
+	io.WriteString(stderr, "\r\x1B[K")
+	if err != nil {
+		return err
+	}
+
+	tty1, err := tty.Open()
+	if err != nil {
+		return err
+	}
+	defer tty1.Close()
+	out := tty1.Output()
+
+	restore, err := tty1.Raw()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	// the alternate screen and the hidden cursor
+	io.WriteString(out, "\x1B[?1049h\x1B[?25l")
+	defer io.WriteString(out, "\x1B[?25h\x1B[?1049l")
+
+	b := &duBrowser{tty: tty1, out: out, node: root}
+	b.reload()
+	for {
+		b.draw()
+		key, err := b.getKey()
+		if err != nil {
+			return err
+		}
+		_, height, err := tty1.Size()
+		if err != nil || height < 4 {
+			height = 4
+		}
+		page := height - 2
+		switch key {
+		case "q", "Q", "\x03":
+			return nil
+		case "\x1B[A", "k", "\x10":
+			if b.cursor > 0 {
+				b.cursor--
+			}
+		case "\x1B[B", "j", "\x0E":
+			if b.cursor < len(b.items)-1 {
+				b.cursor++
+			}
+		case "\x1B[5~":
+			b.cursor -= page
+			if b.cursor < 0 {
+				b.cursor = 0
+			}
+		case "\x1B[6~":
+			b.cursor += page
+			if b.cursor >= len(b.items) {
+				b.cursor = len(b.items) - 1
+			}
+		case "\x1B[H", "\x1B[1~", "g":
+			b.cursor = 0
+		case "\x1B[F", "\x1B[4~", "G":
+			b.cursor = len(b.items) - 1
+		case "\x1B[C", "\r", "l":
+			b.enter()
+		case "\x1B[D", "\x7F", "\b", "h":
+			b.leave()
+		case "d", "\x1B[3~":
+			if err := b.delete(); err != nil {
+				return err
+			}
+		}
+		if b.cursor < 0 {
+			b.cursor = 0
+		}
+	}
+}
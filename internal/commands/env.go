@@ -1,10 +1,14 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+
+	"github.com/nyaosorg/nyagos/internal/shell"
 )
 
 func array2hash(args []string) ([]string, map[string]string) {
@@ -21,13 +25,155 @@ func array2hash(args []string) ([]string, map[string]string) {
 	return []string{}, hash
 }
 
+const envUsage = "Usage: env [-i] [-u NAME] [-C DIR] [--file FILE] [NAME=VALUE]... [COMMAND [ARG]...]"
+
+// unquoteDotEnv returns the value of `"..."` or `'...'`.
+// Escape sequences are available only in double quotations.
+func unquoteDotEnv(value string) (string, error) {
+	quote := value[0]
+	var buffer strings.Builder
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		if c == quote {
+			return buffer.String(), nil
+		}
+		if c == '\\' && quote == '"' && i+1 < len(value) {
+			i++
+			switch value[i] {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			default:
+				c = value[i]
+			}
+		}
+		buffer.WriteByte(c)
+	}
+	return "", fmt.Errorf("%s: quotation not closed", value)
+}
+
+// parseDotEnv reads the lines `NAME=VALUE` of the dotenv file into env.
+// `export` before NAME, comments by `#` and quoted values are supported.
+func parseDotEnv(r io.Reader, env map[string]string) error {
+	sc := bufio.NewScanner(r)
+	for lnum := 1; sc.Scan(); lnum++ {
+		line := strings.TrimSpace(sc.Text())
+		if lnum == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(line[7:])
+		}
+		equalPos := strings.IndexByte(line, '=')
+		if equalPos <= 0 {
+			return fmt.Errorf("line %d: `NAME=VALUE` expected", lnum)
+		}
+		name := strings.TrimSpace(line[:equalPos])
+		value := strings.TrimSpace(line[equalPos+1:])
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			var err error
+			value, err = unquoteDotEnv(value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lnum, err)
+			}
+		} else if pos := strings.Index(value, " #"); pos >= 0 {
+			value = strings.TrimSpace(value[:pos])
+		}
+		env[name] = value
+	}
+	return sc.Err()
+}
+
+// parseEnvOptions returns the options of `env` and the command with its arguments.
+func parseEnvOptions(args []string) (*shell.SpawnOption, []string, error) {
+	opt := &shell.SpawnOption{Env: map[string]string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		key, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			if pos := strings.IndexByte(arg, '='); pos >= 0 {
+				key, value, hasValue = arg[:pos], arg[pos+1:], true
+			}
+		}
+		switch key {
+		case "-i", "-", "--ignore-environment":
+			opt.Clear = true
+			continue
+		case "-u", "--unset", "-C", "--chdir", "--file":
+		default:
+			rest, hash := array2hash(args[i:])
+			for name, val := range hash {
+				opt.Env[name] = val
+			}
+			if len(rest) > 0 && strings.HasPrefix(rest[0], "-") {
+				return nil, nil, fmt.Errorf("env: %s: unknown option\n%s", rest[0], envUsage)
+			}
+			return opt, rest, nil
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("env: %s: value missing\n%s", key, envUsage)
+			}
+			i++
+			value = args[i]
+		}
+		switch key {
+		case "-u", "--unset":
+			opt.Unset = append(opt.Unset, value)
+		case "-C", "--chdir":
+			opt.Dir = value
+		default:
+			fd, err := os.Open(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			err = parseDotEnv(fd, opt.Env)
+			fd.Close()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", value, err)
+			}
+		}
+	}
+	return opt, nil, nil
+}
+
 func cmdEnv(ctx context.Context, cmd Param) (int, error) {
-	args, hash := array2hash(cmd.Args()[1:])
+	opt, args, err := parseEnvOptions(cmd.Args()[1:])
+	if err != nil {
+		return 1, err
+	}
 	if len(args) <= 0 {
-		for _, val := range os.Environ() {
+		for _, val := range opt.Environ() {
 			fmt.Fprintln(cmd.Out(), val)
 		}
 		return 0, nil
 	}
-	return cmd.Spawnlpe(ctx, args, args, hash)
+	var rawargs []string
+	if raw := cmd.RawArgs(); len(raw) == len(cmd.Args()) {
+		rawargs = raw[len(raw)-len(args):]
+	}
+	return cmd.SpawnWith(ctx, args, rawargs, opt)
+}
+
+func envRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	opt, _, err := parseEnvOptions(cmd.Args()[1:])
+	if err != nil {
+		return nil, err
+	}
+	records := []Record{}
+	for _, val := range opt.Environ() {
+		// Windows has the variables like `=C:=C:\`
+		equalPos := strings.IndexRune(val[1:], '=') + 1
+		if equalPos <= 0 {
+			continue
+		}
+		records = append(records, Record{{"name", val[:equalPos]}, {"value", val[equalPos+1:]}})
+	}
+	return records, nil
 }
@@ -0,0 +1,38 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvFile(t *testing.T) {
+	dotenv := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(dotenv, []byte("\uFEFF# comment\n"+
+		"export A=\"x\\ty\" # comment\n"+
+		"B=plain # comment\n"+
+		"C='$literal'\n"), 0666)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	records := testRecords(t, "env", "-i", "--file", dotenv, "B=override", "D=4")
+	expect := []string{"A", "x\ty", "B", "override", "C", "$literal", "D", "4"}
+	if len(records)*2 != len(expect) {
+		t.Fatalf("env: %v", records)
+	}
+	for i, r := range records {
+		m := r.Map()
+		if m["name"] != expect[i*2] || m["value"] != expect[i*2+1] {
+			t.Fatalf("env: %v", records)
+		}
+	}
+}
+
+func TestEnvUnset(t *testing.T) {
+	t.Setenv("NYAGOS_ENV_TEST", "1")
+	for _, r := range testRecords(t, "env", "-u", "NYAGOS_ENV_TEST") {
+		if r.Map()["name"] == "NYAGOS_ENV_TEST" {
+			t.Fatal("env -u: not removed")
+		}
+	}
+}
@@ -0,0 +1,4 @@
+package commands
+
+// CopyResumeSize lets the tests copy small files via DST.part.
+var CopyResumeSize = &copyResumeSize
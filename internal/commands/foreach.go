@@ -10,9 +10,24 @@ import (
 	"github.com/nyaosorg/nyagos/internal/texts"
 )
 
-var startList = map[string]bool{
-	"foreach": true,
-	"if":      true,
+// BlockNest returns how the line changes the nest level of the blocks:
+// +1 for `foreach` and block-`if`, -1 for `end` and `endif`, otherwise 0.
+func BlockNest(line string) int {
+	args := texts.SplitLikeShellString(line)
+	if len(args) <= 0 {
+		return 0
+	}
+	switch strings.ToLower(args[0]) {
+	case "foreach":
+		return 1
+	case "if":
+		if isBlockIf(args) {
+			return 1
+		}
+	case "end", "endif":
+		return -1
+	}
+	return 0
 }
 
 func cmdForeach(ctx context.Context, cmd Param) (int, error) {
@@ -29,14 +44,9 @@ func cmdForeach(ctx context.Context, cmd Param) (int, error) {
 			}
 			break
 		}
-		name := strings.ToLower(texts.FirstWord(line))
-		if _, ok := startList[name]; ok {
-			nest++
-		} else if name == "end" {
-			nest--
-			if nest == 0 {
-				break
-			}
+		nest += BlockNest(line)
+		if nest == 0 {
+			break
 		}
 		bufstream.Add(line)
 	}
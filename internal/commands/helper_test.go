@@ -0,0 +1,68 @@
+package commands_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+	"github.com/nyaosorg/nyagos/internal/shell"
+)
+
+// testCommand returns the command of a new shell to run args.
+// It is closed when the test finishes.
+func testCommand(t *testing.T, args ...string) *shell.Cmd {
+	t.Helper()
+	sh := shell.New()
+	t.Cleanup(sh.Close)
+	cmd := sh.Command()
+	t.Cleanup(cmd.Close)
+	cmd.SetArgs(args)
+	return cmd
+}
+
+// testRun runs the builtin command with the standard output to stdout.
+// When stdout is nil, the output is discarded.
+func testRun(t *testing.T, stdout *os.File, args ...string) (int, error) {
+	t.Helper()
+	if stdout == nil {
+		null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer null.Close()
+		stdout = null
+	}
+	cmd := testCommand(t, args...)
+	cmd.Stdio[1] = stdout
+	rc, _, err := commands.Exec(context.Background(), cmd)
+	return rc, err
+}
+
+// testExec runs the builtin command and fails unless it succeeds.
+func testExec(t *testing.T, args ...string) {
+	t.Helper()
+	if rc, err := testRun(t, nil, args...); err != nil || rc != 0 {
+		t.Fatalf("%v: %d %v", args, rc, err)
+	}
+}
+
+// testOutput runs the builtin command and returns its standard output.
+func testOutput(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = testRun(t, out, args...)
+	out.Close()
+	if err != nil {
+		t.Fatalf("%v: %s", args, err.Error())
+	}
+	result, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return string(result)
+}
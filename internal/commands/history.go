@@ -2,9 +2,56 @@ package commands
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/nyaosorg/nyagos/internal/history"
 )
 
 func cmdHistory(ctx context.Context, args Param) (int, error) {
 	return history.CmdHistory(ctx, args, args.GetHistory())
 }
+
+type historyLineGetter interface {
+	GetAt(int) *history.Line
+}
+
+// historyRecords returns all the history or the last N lines with `history N`
+func historyRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	hisObj := cmd.GetHistory()
+	start := 0
+	if len(cmd.Args()) >= 2 {
+		num, err := strconv.Atoi(cmd.Arg(1))
+		if err != nil {
+			return nil, fmt.Errorf("history: %s not a number", cmd.Arg(1))
+		}
+		if num < 0 {
+			num = -num
+		}
+		if hisObj.Len() > num {
+			start = hisObj.Len() - num
+		}
+	}
+	getter, ok := hisObj.(historyLineGetter)
+	records := make([]Record, 0, hisObj.Len()-start)
+	for i := start; i < hisObj.Len(); i++ {
+		if !ok {
+			records = append(records, Record{{"number", i}, {"text", hisObj.DumpAt(i)}})
+			continue
+		}
+		line := getter.GetAt(i)
+		stamp := ""
+		if !line.Stamp.IsZero() {
+			stamp = line.Stamp.Format(time.RFC3339)
+		}
+		records = append(records, Record{
+			{"number", i},
+			{"text", line.Text},
+			{"dir", line.Dir},
+			{"time", stamp},
+			{"pid", line.Pid},
+		})
+	}
+	return records, nil
+}
@@ -14,56 +14,215 @@ import (
 
 var rxElse = regexp.MustCompile(`(?i)^\s*else`)
 
+// ifTest evaluates a condition of `if`.
+type ifTest func() (bool, error)
+
+// compareOperators are the operators of CMD.EXE comparing numerically
+// when both sides are numbers, otherwise as strings.
+var compareOperators = map[string]func(int) bool{
+	"equ": func(c int) bool { return c == 0 },
+	"neq": func(c int) bool { return c != 0 },
+	"lss": func(c int) bool { return c < 0 },
+	"leq": func(c int) bool { return c <= 0 },
+	"gtr": func(c int) bool { return c > 0 },
+	"geq": func(c int) bool { return c >= 0 },
+}
+
+func compareIfOperands(left, right string, ignoreCase bool) int {
+	if l, err := parseNumber(left); err == nil {
+		if r, err := parseNumber(right); err == nil {
+			return compareNumbers(l, r)
+		}
+	}
+	if ignoreCase {
+		left = strings.ToLower(left)
+		right = strings.ToLower(right)
+	}
+	return strings.Compare(left, right)
+}
+
+// isNewer returns true when the file left is newer than right
+// or only left exists like `test left -nt right`.
+func isNewer(left, right string) bool {
+	l, err := os.Stat(left)
+	if err != nil {
+		return false
+	}
+	r, err := os.Stat(right)
+	if err != nil {
+		return true
+	}
+	return l.ModTime().After(r.ModTime())
+}
+
+// fileTests are the predicates of `-X PATH` like test(1).
+var fileTests = map[string]func(os.FileInfo) bool{
+	"-e": func(os.FileInfo) bool { return true },
+	"-d": func(f os.FileInfo) bool { return f.IsDir() },
+	"-f": func(f os.FileInfo) bool { return f.Mode().IsRegular() },
+	"-s": func(f os.FileInfo) bool { return f.Size() > 0 },
+	"-x": lsIsExecutable,
+}
+
+// parseIfTerm parses one condition with the leading `not`s
+// and returns it with the count of the consumed args.
+// It returns 0 as the count when args do not start with a condition.
+func parseIfTerm(args []string, ignoreCase bool) (ifTest, int) {
+	if len(args) >= 2 && strings.EqualFold(args[0], "not") {
+		test, n := parseIfTerm(args[1:], ignoreCase)
+		if n <= 0 {
+			return nil, 0
+		}
+		return func() (bool, error) {
+			status, err := test()
+			return !status, err
+		}, n + 1
+	}
+	if len(args) >= 3 {
+		left, op, right := args[0], args[1], args[2]
+		switch lowerOp := strings.ToLower(op); lowerOp {
+		case "==":
+			return func() (bool, error) {
+				if ignoreCase {
+					return strings.EqualFold(left, right), nil
+				}
+				return left == right, nil
+			}, 3
+		case "=~":
+			return func() (bool, error) {
+				if ignoreCase {
+					right = "(?i)" + right
+				}
+				rx, err := regexp.Compile(right)
+				if err != nil {
+					return false, err
+				}
+				return rx.MatchString(left), nil
+			}, 3
+		case "-nt":
+			return func() (bool, error) { return isNewer(left, right), nil }, 3
+		case "-ot":
+			return func() (bool, error) { return isNewer(right, left), nil }, 3
+		default:
+			if f, ok := compareOperators[lowerOp]; ok {
+				return func() (bool, error) {
+					return f(compareIfOperands(left, right, ignoreCase)), nil
+				}, 3
+			}
+		}
+	}
+	if len(args) >= 2 {
+		operand := args[1]
+		switch strings.ToLower(args[0]) {
+		case "exist":
+			return func() (bool, error) {
+				_, err := os.Stat(operand)
+				return err == nil, nil
+			}, 2
+		case "defined":
+			return func() (bool, error) {
+				_, ok := os.LookupEnv(operand)
+				return ok, nil
+			}, 2
+		case "errorlevel":
+			return func() (bool, error) {
+				num, err := strconv.Atoi(operand)
+				return err == nil && shell.LastErrorLevel >= num, nil
+			}, 2
+		}
+		if f, ok := fileTests[args[0]]; ok {
+			return func() (bool, error) {
+				stat, err := os.Stat(operand)
+				return err == nil && f(stat), nil
+			}, 2
+		}
+	}
+	return nil, 0
+}
+
+// parseIfJoined parses the terms joined with the operator
+// (`and` or `or`) by calling parse for each term.
+func parseIfJoined(args []string, op string, parse func([]string) (ifTest, int)) (ifTest, int) {
+	test, n := parse(args)
+	if n <= 0 {
+		return nil, 0
+	}
+	isOr := op == "or"
+	for n+1 < len(args) && strings.EqualFold(args[n], op) {
+		next, m := parse(args[n+1:])
+		if m <= 0 {
+			break
+		}
+		left := test
+		test = func() (bool, error) {
+			status, err := left()
+			if err != nil || status == isOr {
+				return status, err
+			}
+			return next()
+		}
+		n += m + 1
+	}
+	return test, n
+}
+
+// parseIfCondition parses the condition of `if` where `and` binds
+// tighter than `or`, and returns it with the count of the consumed args.
+func parseIfCondition(args []string, ignoreCase bool) (ifTest, int) {
+	return parseIfJoined(args, "or", func(args []string) (ifTest, int) {
+		return parseIfJoined(args, "and", func(args []string) (ifTest, int) {
+			return parseIfTerm(args, ignoreCase)
+		})
+	})
+}
+
+// isIfOption returns true for the options like `/i`.
+// The longer words starting with `/` are the paths on Unix.
+func isIfOption(arg string) bool {
+	return len(arg) == 2 && arg[0] == '/'
+}
+
+// isBlockIf returns true when args (the fields of the line starting with `if`)
+// have no statement after the condition except `then`.
+func isBlockIf(args []string) bool {
+	args = args[1:]
+	for len(args) > 0 && isIfOption(args[0]) {
+		args = args[1:]
+	}
+	_, n := parseIfCondition(args, false)
+	if n <= 0 {
+		return false
+	}
+	args = args[n:]
+	return len(args) <= 0 || args[0] == "then"
+}
+
 func cmdIf(ctx context.Context, cmd Param) (int, error) {
 	// if "xxx" == "yyy"
-	args := cmd.Args()
-	rawargs := cmd.RawArgs()
-	not := false
+	args := cmd.Args()[1:]
+	rawargs := cmd.RawArgs()[1:]
 	start := 1
 
 	option := map[string]struct{}{}
 
-	for len(args) >= 2 && strings.HasPrefix(args[1], "/") {
-		option[strings.ToLower(args[1])] = struct{}{}
+	for len(args) >= 1 && isIfOption(args[0]) {
+		option[strings.ToLower(args[0])] = struct{}{}
 		args = args[1:]
 		rawargs = rawargs[1:]
 		start++
 	}
+	_, ignoreCase := option["/i"]
 
-	if len(args) >= 2 && strings.EqualFold(args[1], "not") {
-		not = true
-		args = args[1:]
-		rawargs = rawargs[1:]
-		start++
-	}
 	status := false
-	if len(args) >= 4 && args[2] == "==" {
-		if _, ok := option["/i"]; ok {
-			status = strings.EqualFold(args[1], args[3])
-		} else {
-			status = (args[1] == args[3])
-		}
-		args = args[4:]
-		rawargs = rawargs[4:]
-		start += 3
-	} else if len(args) >= 3 && strings.EqualFold(args[1], "exist") {
-		_, err := os.Stat(args[2])
-		status = (err == nil)
-		args = args[3:]
-		rawargs = rawargs[3:]
-		start += 2
-	} else if len(args) >= 3 && strings.EqualFold(args[1], "errorlevel") {
-		num, err := strconv.Atoi(args[2])
-		if err == nil {
-			status = (shell.LastErrorLevel >= num)
+	if test, n := parseIfCondition(args, ignoreCase); n > 0 {
+		var err error
+		status, err = test()
+		if err != nil {
+			return 1, err
 		}
-		args = args[3:]
-		rawargs = rawargs[3:]
-		start += 2
-	}
-
-	if not {
-		status = !status
+		args = args[n:]
+		rawargs = rawargs[n:]
+		start += n
 	}
 
 	thenBuffer := shell.BufStream{}
@@ -105,15 +264,11 @@ func cmdIf(ctx context.Context, cmd Param) (int, error) {
 		if len(args) <= 0 {
 			continue
 		}
-		name := strings.ToLower(args[0])
-		if _, ok := startList[name]; ok {
-			nest++
-		} else if name == "end" || name == "endif" {
-			nest--
-			if nest == 0 {
-				break
-			}
-		} else if name == "else" {
+		nest += BlockNest(line)
+		if nest == 0 {
+			break
+		}
+		if strings.EqualFold(args[0], "else") {
 			if nest == 1 {
 				elsePart = true
 				os.Setenv("PROMPT", "else>")
@@ -0,0 +1,62 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIfCondition(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(file, []byte("x"), 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.WriteFile(empty, nil, 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(empty, old, old); err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Setenv("IF_DEFINED", "1")
+	for _, c := range []struct {
+		cond   []string
+		expect bool
+	}{
+		{[]string{"10", "GTR", "9"}, true},
+		{[]string{"10", "lss", "9.5"}, false},
+		{[]string{"0x10", "EQU", "16"}, true},
+		{[]string{"abc", "LSS", "abd"}, true},
+		{[]string{"/i", "ABC", "EQU", "abc"}, true},
+		{[]string{"ABC", "NEQ", "abc"}, true},
+		{[]string{"2", "LEQ", "2"}, true},
+		{[]string{"2", "GEQ", "3"}, false},
+		{[]string{"defined", "IF_DEFINED"}, true},
+		{[]string{"not", "defined", "IF_NOT_DEFINED"}, true},
+		{[]string{"-d", dir, "and", "-f", file}, true},
+		{[]string{"-f", dir, "or", "-e", empty}, true},
+		{[]string{"-s", empty}, false},
+		{[]string{"-s", file}, true},
+		{[]string{file, "-nt", empty}, true},
+		{[]string{file, "-ot", empty}, false},
+		{[]string{file, "-nt", filepath.Join(dir, "none")}, true},
+		{[]string{"hello123", "=~", "^hel+o[0-9]+$"}, true},
+		{[]string{"/i", "HELLO", "=~", "^hello$"}, true},
+		{[]string{"1", "==", "2", "or", "1", "==", "1", "and", "2", "==", "2"}, true},
+		{[]string{"1", "==", "1", "or", "1", "==", "2", "and", "2", "==", "3"}, true},
+		{[]string{"1", "==", "2", "and", "1", "==", "1", "or", "3", "==", "4"}, false},
+	} {
+		args := append([]string{"if"}, c.cond...)
+		args = append(args, "echo", "yes")
+		expect := ""
+		if c.expect {
+			expect = "yes\n"
+		}
+		if result := testOutput(t, args...); result != expect {
+			t.Fatalf("%v: %q (expect %q)", c.cond, result, expect)
+		}
+	}
+}
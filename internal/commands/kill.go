@@ -5,79 +5,328 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mitchellh/go-ps"
 )
 
-func cmdKill(ctx context.Context, cmd Param) (int, error) {
-	args := cmd.Args()
-	if len(args) < 2 {
-		return 1, fmt.Errorf("usage: %s PID", args[0])
+type killOptions struct {
+	signal   syscall.Signal
+	hasSig   bool
+	tree     bool
+	group    bool
+	grace    time.Duration
+	wait     bool
+	pattern  *regexp.Regexp
+	fullLine bool
+	rest     []string
+}
+
+// parseSignal converts TERM, SIGTERM or 15 to the signal.
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return syscall.Signal(n), nil
+	}
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	if sig, ok := signalTable[name]; ok {
+		return sig, nil
 	}
+	return 0, fmt.Errorf("%s: unknown signal", s)
+}
 
-	pid, err := strconv.Atoi(cmd.Arg(1))
-	if err != nil {
-		return 1, fmt.Errorf("%s: arguments must be process ID", args[0])
+func parseKillOptions(args []string) (*killOptions, error) {
+	opt := &killOptions{signal: syscall.SIGKILL}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() (string, error) {
+			if pos := strings.IndexByte(arg, '='); pos >= 0 {
+				return arg[pos+1:], nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s: value missing", arg)
+			}
+			i++
+			return args[i], nil
+		}
+		switch {
+		case arg == "-t":
+			opt.tree = true
+		case arg == "-g":
+			opt.group = true
+		case arg == "-f":
+			opt.fullLine = true
+		case arg == "--wait":
+			opt.wait = true
+		case arg == "--grace" || strings.HasPrefix(arg, "--grace="):
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			sec, err := strconv.ParseFloat(v, 64)
+			if err != nil || sec < 0 {
+				return nil, fmt.Errorf("--grace: %s: invalid seconds", v)
+			}
+			opt.grace = time.Duration(sec * float64(time.Second))
+		case arg == "-r":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			rx, err := regexp.Compile(v)
+			if err != nil {
+				return nil, err
+			}
+			opt.pattern = rx
+		case arg == "-s":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if opt.signal, err = parseSignal(v); err != nil {
+				return nil, err
+			}
+			opt.hasSig = true
+		case len(arg) > 1 && arg[0] == '-':
+			sig, err := parseSignal(arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			opt.signal = sig
+			opt.hasSig = true
+		default:
+			opt.rest = append(opt.rest, arg)
+		}
+	}
+	// The grace period is given to the signal which can be handled.
+	if opt.grace > 0 && !opt.hasSig {
+		opt.signal = syscall.SIGTERM
 	}
-	if pid == os.Getpid() {
-		return 1, errors.New("can not kill the killer self")
+	return opt, nil
+}
+
+// waitProcesses waits until the processes exit or the timeout (0: never)
+// and returns the processes alive yet.
+func waitProcesses(ctx context.Context, pids []int, timeout time.Duration) ([]int, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
 	}
-	process, err := os.FindProcess(pid)
+	for {
+		alive := pids[:0]
+		for _, pid := range pids {
+			if isProcessAlive(pid) {
+				alive = append(alive, pid)
+			}
+		}
+		pids = alive
+		if len(pids) <= 0 || (timeout > 0 && time.Now().After(deadline)) {
+			return pids, nil
+		}
+		if ctx != nil {
+			select {
+			case <-ctx.Done():
+				return pids, errCtrlC
+			case <-time.After(100 * time.Millisecond):
+			}
+		} else {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
+// killProcesses sends the signal to the processes, escalates to SIGKILL
+// after the grace period and waits for them with --wait.
+// The processes in names are reported when the signal is sent.
+func killProcesses(ctx context.Context, cmd Param, opt *killOptions, pids []int, names map[int]string) (int, error) {
+	errorlevel := 0
+	myself := os.Getpid()
+	targets := make([]int, 0, len(pids))
+	for _, pid := range pids {
+		if pid <= 0 {
+			fmt.Fprintf(cmd.Err(), "%d: invalid process ID\n", pid)
+			errorlevel = 1
+			continue
+		}
+		if pid == myself {
+			fmt.Fprintln(cmd.Err(), "can not kill the killer self")
+			errorlevel = 1
+			continue
+		}
+		targets = append(targets, pid)
+	}
+	sent := make([]int, 0, len(targets))
+	for _, pid := range targets {
+		var err error
+		if opt.group {
+			err = sendSignalToGroup(pid, opt.signal)
+		} else {
+			err = sendSignal(pid, opt.signal)
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.Err(), "%d: %s\n", pid, err.Error())
+			errorlevel = 1
+			continue
+		}
+		if name, ok := names[pid]; ok {
+			fmt.Fprintf(cmd.Out(), "Killed [%d] %s.\n", pid, name)
+		}
+		sent = append(sent, pid)
+	}
+	if opt.grace > 0 && opt.signal != syscall.SIGKILL {
+		alive, err := waitProcesses(ctx, append([]int{}, sent...), opt.grace)
+		if err != nil {
+			return 1, err
+		}
+		for _, pid := range alive {
+			fmt.Fprintf(cmd.Err(), "%d: still alive after %v, sending SIGKILL\n", pid, opt.grace)
+			if err := sendSignal(pid, syscall.SIGKILL); err != nil {
+				fmt.Fprintf(cmd.Err(), "%d: %s\n", pid, err.Error())
+				errorlevel = 1
+			}
+		}
+	}
+	if opt.wait {
+		if _, err := waitProcesses(ctx, sent, 0); err != nil {
+			return 1, err
+		}
+	}
+	return errorlevel, nil
+}
+
+// expandTree adds the descendants of pids for -t, the deepest ones first.
+// nyagos itself and its ancestors can not be the roots, and the walk
+// does not go into them so that the children of nyagos are not killed.
+func expandTree(pids []int) ([]int, error) {
+	processes, err := ps.Processes()
 	if err != nil {
-		return 1, err
+		return nil, err
 	}
+	children := map[int][]int{}
+	parent := map[int]int{}
+	for _, p := range processes {
+		if p.PPid() != p.Pid() {
+			children[p.PPid()] = append(children[p.PPid()], p.Pid())
+			parent[p.Pid()] = p.PPid()
+		}
+	}
+	protected := map[int]bool{}
+	for pid := os.Getpid(); pid > 0 && !protected[pid]; pid = parent[pid] {
+		protected[pid] = true
+	}
+	result := []int{}
+	var walk func(int)
+	walk = func(pid int) {
+		for _, child := range children[pid] {
+			if !protected[child] {
+				walk(child)
+				result = append(result, child)
+			}
+		}
+	}
+	for _, pid := range pids {
+		if protected[pid] {
+			return nil, fmt.Errorf("%d: can not kill the tree which has the killer self", pid)
+		}
+		walk(pid)
+		result = append(result, pid)
+	}
+	return result, nil
+}
 
-	err = process.Kill()
+func cmdKill(ctx context.Context, cmd Param) (int, error) {
+	args := cmd.Args()
+	opt, err := parseKillOptions(args[1:])
 	if err != nil {
 		return 1, err
 	}
-	return 0, nil
+	if len(opt.rest) < 1 {
+		return 1, fmt.Errorf("usage: %s [-SIGNAL] [-t|-g] [--grace=SEC] [--wait] PID...", args[0])
+	}
+	pids := make([]int, 0, len(opt.rest))
+	for _, s := range opt.rest {
+		pid, err := strconv.Atoi(s)
+		if err != nil {
+			return 1, fmt.Errorf("%s: arguments must be process ID", args[0])
+		}
+		// 0 and the negative numbers mean the process groups for kill(2).
+		if pid <= 0 {
+			return 1, fmt.Errorf("%s: %d: invalid process ID", args[0], pid)
+		}
+		if pid == os.Getpid() {
+			return 1, errors.New("can not kill the killer self")
+		}
+		pids = append(pids, pid)
+	}
+	if opt.tree {
+		if pids, err = expandTree(pids); err != nil {
+			return 1, err
+		}
+	}
+	return killProcesses(ctx, cmd, opt, pids, nil)
 }
 
 func cmdKillAll(ctx context.Context, cmd Param) (int, error) {
 	args := cmd.Args()
-	if len(args) < 2 {
-		return 1, fmt.Errorf("usage: %s {ExecutableName...}", args[0])
+	opt, err := parseKillOptions(args[1:])
+	if err != nil {
+		return 1, err
+	}
+	if len(opt.rest) < 1 && opt.pattern == nil {
+		return 1, fmt.Errorf("usage: %s [-SIGNAL] [-f] [-r REGEXP] {ExecutableName...}", args[0])
 	}
 	processes, err := ps.Processes()
 	if err != nil {
 		return 1, err
 	}
-	keywords := make([]string, 0, len(args)-1)
-	for _, w := range args[1:] {
+	keywords := make([]string, 0, len(opt.rest))
+	for _, w := range opt.rest {
 		if len(w) <= 1 {
 			fmt.Fprintf(cmd.Err(), "%s: ExecutableName must be more than 1-character\n", w)
 			continue
 		}
 		keywords = append(keywords, strings.ToUpper(w))
 	}
-	errorlevel := 1
 	myself := os.Getpid()
+	pids := []int{}
+	names := map[int]string{}
 	for _, p := range processes {
 		if p.Pid() == myself {
 			continue
 		}
-		name := strings.ToUpper(p.Executable())
+		name := p.Executable()
+		if opt.fullLine {
+			if d := getProcessDetail(p.Pid()); d != nil && d.cmdline != "" {
+				name = d.cmdline
+			}
+		}
+		matched := opt.pattern != nil && opt.pattern.MatchString(name)
+		upperName := strings.ToUpper(name)
 		for _, w := range keywords {
-			if strings.Contains(name, w) {
-				process1, err := os.FindProcess(p.Pid())
-				if err == nil {
-					err = process1.Kill()
-				}
-				if err == nil {
-					fmt.Fprintf(cmd.Out(), "Killed [%d] %s.\n", p.Pid(), p.Executable())
-					if errorlevel == 1 {
-						errorlevel = 0
-					}
-				} else {
-					fmt.Fprintf(cmd.Err(), "Fail to kill [%d] %s because %s.\n", p.Pid(), p.Executable(), err.Error())
-					errorlevel = 2
-				}
+			if matched {
 				break
 			}
+			matched = strings.Contains(upperName, w)
+		}
+		if matched {
+			pids = append(pids, p.Pid())
+			names[p.Pid()] = p.Executable()
 		}
 	}
-	return errorlevel, nil
+	if len(pids) <= 0 {
+		return 1, nil
+	}
+	if opt.tree {
+		if pids, err = expandTree(pids); err != nil {
+			return 1, err
+		}
+	}
+	errorlevel, err := killProcesses(ctx, cmd, opt, pids, names)
+	if errorlevel != 0 {
+		errorlevel = 2
+	}
+	return errorlevel, err
 }
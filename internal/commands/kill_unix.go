@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"errors"
+	"syscall"
+)
+
+var signalTable = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP,
+}
+
+func sendSignal(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+// sendSignalToGroup sends the signal to the process group which pid belongs to.
+func sendSignalToGroup(pid int, sig syscall.Signal) error {
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return err
+	}
+	if pgid == syscall.Getpgrp() {
+		return errors.New("can not kill the group of the killer self")
+	}
+	return syscall.Kill(-pgid, sig)
+}
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	if err != nil && err != syscall.EPERM {
+		return false
+	}
+	return !isZombie(pid)
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package commands_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestKillTreeOfAncestor(t *testing.T) {
+	// SIGCONT does no harm even if the parent is signaled.
+	if _, err := testRun(t, nil, "kill", "-CONT", "-t", strconv.Itoa(os.Getppid())); err == nil {
+		t.Fatal("kill -t: the ancestor of the killer self is accepted")
+	}
+}
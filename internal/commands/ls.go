@@ -1,9 +1,23 @@
 package commands
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/mattn/go-isatty"
+
+	"github.com/nyaosorg/go-box/v2"
+	"github.com/nyaosorg/go-windows-findfile"
+	"github.com/nyaosorg/nyagos/internal/nodos"
 )
 
 func formatByHumanize(size int64) string {
@@ -13,3 +27,549 @@ func formatByHumanize(size int64) string {
 	}
 	return strings.ToUpper(strings.ReplaceAll(s, " ", ""))
 }
+
+const (
+	_              = iota
+	optionStripDir = (1 << iota)
+	optionLong
+	optionIndicator
+	optionColor
+	optionAll
+	optionTime
+	optionReserve
+	optionRecursive
+	optionOne
+	optionHelp
+	optionSizeSort
+	optionHuman
+	optionNotRecursive
+	optionDereference
+)
+
+type fileInfoT struct {
+	name        string
+	os.FileInfo // anonymous
+}
+
+const (
+	ansiExec     = "\x1B[35;1m"
+	ansiDir      = "\x1B[32;1m"
+	ansiNorm     = "\x1B[39;1m"
+	ansiReadOnly = "\x1B[33;1m"
+	ansiHidden   = "\x1B[34;1m"
+	ansiEnd      = "\x1B[0m"
+)
+
+func chkCancel(ctx context.Context) error {
+	if ctx != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+	return nil
+}
+
+func (f fileInfoT) Name() string { return f.name }
+
+// lsDecorate returns the color sequences enclosing the name of f
+// and the indicator for -F.
+func lsDecorate(f os.FileInfo, flag int) (prefix, postfix, indicator string) {
+	if (flag & optionColor) != 0 {
+		prefix = ansiNorm
+		postfix = ansiEnd
+	}
+	if f.IsDir() {
+		if (flag & optionColor) != 0 {
+			prefix = ansiDir
+		}
+		indicator = "/"
+	}
+	if lsIsReadOnly(f) && (flag&optionColor) != 0 {
+		prefix = ansiReadOnly
+	}
+	if lsIsExecutable(f) {
+		if (flag & optionColor) != 0 {
+			prefix = ansiExec
+		}
+		indicator = "*"
+	}
+	if lsIsHidden(f) && (flag&optionColor) != 0 {
+		prefix = ansiHidden
+	}
+	if lsIsLink(f) {
+		indicator = "@"
+	}
+	return
+}
+
+func lsBox(ctx context.Context, folder string, nodes []os.FileInfo, flag int, out io.Writer) error {
+	_nodes := make([]string, len(nodes))
+	for key, val := range nodes {
+		prefix, postfix, indicator := lsDecorate(val, flag)
+		if indicator != "" && (flag&optionIndicator) != 0 {
+			_nodes[key] = prefix + val.Name() + postfix + indicator
+		} else {
+			_nodes[key] = prefix + val.Name()
+		}
+	}
+	isSucceeded := box.Print(ctx, _nodes, out)
+	if (flag & optionColor) != 0 {
+		io.WriteString(out, ansiEnd)
+	}
+	if !isSucceeded {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func keta(n int64) int {
+	count := 1
+	for n >= 10 {
+		count++
+		n /= 10
+	}
+	return count
+}
+
+// lsColumns is the widths of the columns on the long format.
+type lsColumns struct {
+	size  int
+	links int
+	owner int
+	group int
+}
+
+func lsLong(ctx context.Context, folder string, nodes []os.FileInfo, flag int, out io.Writer) error {
+	var width lsColumns
+	if (flag & optionHuman) != 0 {
+		for _, finfo := range nodes {
+			width1 := len(formatByHumanize(finfo.Size()))
+			if width1 > width.size {
+				width.size = width1
+			}
+		}
+	} else {
+		size := int64(1)
+		for _, finfo := range nodes {
+			if finfo.Size() > size {
+				size = finfo.Size()
+			}
+		}
+		width.size = keta(size)
+	}
+	for _, finfo := range nodes {
+		width.measure(folder, finfo)
+	}
+	for _, finfo := range nodes {
+		lsOneLong(folder, finfo, flag, &width, out)
+		if err := chkCancel(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lsSimple(ctx context.Context, folder string, nodes []os.FileInfo, flag int, out io.Writer) error {
+	for _, f := range nodes {
+		io.WriteString(out, f.Name())
+		if (flag & optionIndicator) != 0 {
+			_, _, indicator := lsDecorate(f, flag)
+			io.WriteString(out, indicator)
+		}
+		fmt.Fprintln(out)
+		if err := chkCancel(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fileInfoCollection struct {
+	flag  int
+	nodes []os.FileInfo
+}
+
+func (f fileInfoCollection) Len() int {
+	return len(f.nodes)
+}
+
+func (f fileInfoCollection) Less(i, j int) bool {
+	var result bool
+	if (f.flag & optionTime) != 0 {
+		result = f.nodes[i].ModTime().After(f.nodes[j].ModTime())
+		if !result && !f.nodes[i].ModTime().Before(f.nodes[j].ModTime()) {
+			result = (f.nodes[i].Name() < f.nodes[j].Name())
+		}
+	} else if (f.flag & optionSizeSort) != 0 {
+		diff := f.nodes[i].Size() - f.nodes[j].Size()
+		if diff != 0 {
+			result = (diff < 0)
+		} else {
+			result = (f.nodes[i].Name() < f.nodes[j].Name())
+		}
+	} else {
+		result = (f.nodes[i].Name() < f.nodes[j].Name())
+	}
+	if (f.flag & optionReserve) != 0 {
+		result = !result
+	}
+	return result
+}
+func (f fileInfoCollection) Swap(i, j int) {
+	f.nodes[i], f.nodes[j] = f.nodes[j], f.nodes[i]
+}
+
+// lsReadFolder returns the files in the folder sorted and,
+// with -R, the names of the sub-folders.
+func lsReadFolder(ctx context.Context, folder string, flag int) ([]os.FileInfo, []string, error) {
+	var folders []string = nil
+	if (flag & optionRecursive) != 0 {
+		folders = make([]string, 0)
+	}
+	tmp := make([]os.FileInfo, 0)
+
+	var wildcard string
+	if folder == "" {
+		wildcard = "*"
+	} else {
+		wildcard = nodos.Join(folder, "*")
+	}
+	var canceled error
+	findfile.Walk(wildcard, func(f *findfile.FileInfo) bool {
+		if err := chkCancel(ctx); err != nil {
+			canceled = err
+			return false
+		}
+		if (flag & optionAll) == 0 {
+			if strings.HasPrefix(f.Name(), ".") || lsIsHidden(f) {
+				return true
+			}
+		}
+		if f.IsDir() && folders != nil && f.Name() != "." && f.Name() != ".." {
+			folders = append(folders, f.Name())
+		} else {
+			tmp = append(tmp, f)
+		}
+		return true
+	})
+	if canceled != nil {
+		return nil, nil, canceled
+	}
+	if (flag & optionAll) != 0 {
+		tmp = append(tmp, lsDots(folder)...)
+	}
+	sort.Sort(fileInfoCollection{flag: flag, nodes: tmp})
+	return tmp, folders, nil
+}
+
+func lsFolder(ctx context.Context, folder string, flag int, out io.Writer) error {
+	_folder := lsStatName(folder)
+	_ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	nodes, folders, err := lsReadFolder(_ctx, folder, flag)
+	if err != nil {
+		cancel()
+		return err
+	}
+	if (flag & optionLong) != 0 {
+		err = lsLong(_ctx, _folder, nodes, optionStripDir|flag, out)
+	} else if (flag & optionOne) != 0 {
+		err = lsSimple(_ctx, _folder, nodes, optionStripDir|flag, out)
+	} else {
+		err = lsBox(_ctx, _folder, nodes, optionStripDir|flag, out)
+	}
+	cancel()
+	if err != nil {
+		return err
+	}
+	if len(folders) > 0 {
+		for _, f1 := range folders {
+			if err := chkCancel(ctx); err != nil {
+				return err
+			}
+			f1fullpath := nodos.Join(folder, f1)
+			fmt.Fprintf(out, "\n%s:\n", f1fullpath)
+			if err := lsFolder(ctx, f1fullpath, flag, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func lsCore(ctx context.Context, paths []string, flag int, out io.Writer, errout io.Writer) error {
+	if len(paths) <= 0 {
+		return lsFolder(ctx, "", flag, out)
+	}
+	dirs := make([]string, 0)
+	printCount := 0
+	files := make([]os.FileInfo, 0)
+	for _, name := range paths {
+		if err := chkCancel(ctx); err != nil {
+			return err
+		}
+		nameStat := lsStatName(name)
+		var status os.FileInfo
+		var err error
+		if (flag & optionDereference) != 0 {
+			status, err = os.Stat(nameStat)
+		} else {
+			status, err = os.Lstat(nameStat)
+		}
+		if err != nil {
+			if os.IsNotExist(err) {
+				fmt.Fprintf(errout, "ls: %s not exist.\n", nameStat)
+			} else if _, ok := err.(*os.PathError); ok {
+				fmt.Fprintf(errout, "ls: %s: Path Error.\n", nameStat)
+			} else {
+				fmt.Fprintf(errout, "ls: %s\n", err.Error())
+			}
+			continue
+		} else if status.IsDir() && (flag&optionNotRecursive) == 0 {
+			dirs = append(dirs, name)
+		} else {
+			files = append(files, &fileInfoT{filepath.Clean(name), status})
+		}
+	}
+	if len(files) > 0 {
+		nodesArray := fileInfoCollection{flag: flag, nodes: files}
+		sort.Sort(nodesArray)
+		var err error
+		if (flag & optionLong) != 0 {
+			err = lsLong(ctx, ".", files, flag, out)
+		} else if (flag & optionOne) != 0 {
+			err = lsSimple(ctx, ".", files, flag, out)
+		} else {
+			err = lsBox(ctx, ".", files, flag, out)
+		}
+		if err != nil {
+			return err
+		}
+		printCount = len(files)
+	}
+	for _, name := range dirs {
+		if len(paths) > 1 {
+			if printCount > 0 {
+				io.WriteString(out, "\n")
+			}
+			fmt.Fprintf(out, "%s:\n", name)
+		}
+		err := lsFolder(ctx, name, flag, out)
+		if err != nil {
+			return err
+		}
+		printCount++
+	}
+	return nil
+}
+
+var option = map[rune](func(*int) error){
+	'l': func(flag *int) error {
+		*flag |= optionLong
+		return nil
+	},
+	'F': func(flag *int) error {
+		*flag |= optionIndicator
+		return nil
+	},
+	'o': func(flag *int) error {
+		*flag |= optionColor
+		return nil
+	},
+	'a': func(flag *int) error {
+		*flag |= optionAll
+		return nil
+	},
+	't': func(flag *int) error {
+		*flag |= optionTime
+		return nil
+	},
+	'r': func(flag *int) error {
+		*flag |= optionReserve
+		return nil
+	},
+	'R': func(flag *int) error {
+		*flag |= optionRecursive
+		return nil
+	},
+	'1': func(flag *int) error {
+		*flag |= optionOne
+		return nil
+	},
+	'h': func(flag *int) error {
+		*flag |= optionHuman
+		return nil
+	},
+	'?': func(flag *int) error {
+		*flag |= optionHelp
+		return nil
+	},
+	'S': func(flag *int) error {
+		*flag |= optionSizeSort
+		return nil
+	},
+	'd': func(flag *int) error {
+		*flag |= optionNotRecursive
+		return nil
+	},
+	'L': func(flag *int) error {
+		*flag |= optionDereference
+		return nil
+	},
+}
+
+// OptionError is the error when the given option does not exist in the specification.
+type OptionError struct {
+	Option rune
+}
+
+func (err OptionError) Error() string {
+	return fmt.Sprintf("-%c: No such option", err.Option)
+}
+
+func cmdLs(ctx context.Context, cmd Param) (int, error) {
+	return Ls(ctx, cmd.Args(), cmd.Out(), cmd.Err(), cmd.Term())
+}
+
+// parseLsOptions returns the flags and the paths given by args
+// which do not include the command name.
+func parseLsOptions(args []string) (int, []string, error) {
+	flag := 0
+	paths := make([]string, 0)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			for _, o := range arg[1:] {
+				setter, ok := option[o]
+				if !ok {
+					return 0, nil, OptionError{Option: o}
+				}
+				if err := setter(&flag); err != nil {
+					return 0, nil, err
+				}
+			}
+		} else {
+			paths = append(paths, arg)
+		}
+	}
+	if (flag & optionHelp) != 0 {
+		var message strings.Builder
+		message.WriteString("Usage: ls [-")
+		for optKey := range option {
+			message.WriteRune(optKey)
+		}
+		message.WriteString("] [PATH(s)]...")
+		return 0, nil, errors.New(message.String())
+	}
+	return flag, paths, nil
+}
+
+func Ls(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer, term io.Writer) (int, error) {
+	flag, paths, err := parseLsOptions(args[1:])
+	if err != nil {
+		return 1, err
+	}
+
+	if file, ok := stdout.(*os.File); ok && !isatty.IsTerminal(file.Fd()) {
+		flag |= optionOne
+		flag &^= optionColor
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		flag &^= optionColor
+	}
+
+	// cmd.Term() is colorableTerminal which is not fast.
+	if (flag & optionColor) == 0 {
+		_out := bufio.NewWriter(stdout)
+		defer _out.Flush()
+		stdout = _out
+	} else if stdout == os.Stdout {
+		_out := bufio.NewWriter(term)
+		defer _out.Flush()
+		stdout = _out
+	}
+	if (flag & optionColor) != 0 {
+		io.WriteString(stdout, ansiEnd)
+	}
+	return 0, lsCore(ctx, paths, flag, stdout, stderr)
+}
+
+// lsRecord returns the record of the file for `ls --json`
+func lsRecord(folder string, f os.FileInfo) Record {
+	name := filepath.Base(f.Name())
+	path := f.Name()
+	if folder != "" {
+		path = nodos.Join(folder, name)
+	}
+	kind := "file"
+	target := ""
+	if lsIsLink(f) {
+		kind = "link"
+		if linkTo, err := os.Readlink(lsStatName(path)); err == nil {
+			target = linkTo
+		}
+	} else if f.IsDir() {
+		kind = "dir"
+	}
+	r := Record{
+		{"name", name},
+		{"path", path},
+		{"type", kind},
+		{"size", f.Size()},
+		{"perm", fmt.Sprintf("%04o", f.Mode().Perm())},
+		{"mtime", f.ModTime().Format(time.RFC3339)},
+	}
+	r = append(r, lsOwnerFields(f)...)
+	return append(r, Field{"target", target})
+}
+
+func lsFolderRecords(ctx context.Context, folder string, flag int, records []Record) ([]Record, error) {
+	nodes, folders, err := lsReadFolder(ctx, folder, flag)
+	if err != nil {
+		return records, err
+	}
+	for _, f := range nodes {
+		records = append(records, lsRecord(folder, f))
+	}
+	for _, f1 := range folders {
+		records, err = lsFolderRecords(ctx, nodos.Join(folder, f1), flag, records)
+		if err != nil {
+			return records, err
+		}
+	}
+	return records, nil
+}
+
+func lsRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	flag, paths, err := parseLsOptions(cmd.Args()[1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) <= 0 {
+		return lsFolderRecords(ctx, "", flag, []Record{})
+	}
+	records := []Record{}
+	for _, name := range paths {
+		var status os.FileInfo
+		if (flag & optionDereference) != 0 {
+			status, err = os.Stat(lsStatName(name))
+		} else {
+			status, err = os.Lstat(lsStatName(name))
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.Err(), "ls: %s\n", err.Error())
+			continue
+		}
+		if status.IsDir() && (flag&optionNotRecursive) == 0 {
+			records, err = lsFolderRecords(ctx, name, flag, records)
+			if err != nil {
+				return records, err
+			}
+		} else {
+			records = append(records, lsRecord("", &fileInfoT{filepath.Clean(name), status}))
+		}
+	}
+	return records, nil
+}
+
+// vim:set fenc=utf8 ts=4 sw=4 noet:
@@ -13,7 +13,7 @@ func TestLsMain(t *testing.T) {
 	if err != nil {
 		t.Fatalf("ls .: %s", err.Error())
 	}
-	_, err = commands.Ls(context.Background(), []string{"-l", "ls_test.go"}, io.Discard, io.Discard, io.Discard)
+	_, err = commands.Ls(context.Background(), []string{"-l", "ls.go"}, io.Discard, io.Discard, io.Discard)
 	if err != nil {
 		t.Fatalf("ls *: %s", err.Error())
 	}
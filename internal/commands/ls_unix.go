@@ -0,0 +1,195 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	lsUserNames  = map[uint32]string{}
+	lsGroupNames = map[uint32]string{}
+)
+
+func lsUserName(uid uint32) string {
+	if name, ok := lsUserNames[uid]; ok {
+		return name
+	}
+	id := strconv.FormatUint(uint64(uid), 10)
+	name := id
+	if u, err := user.LookupId(id); err == nil {
+		name = u.Username
+	}
+	lsUserNames[uid] = name
+	return name
+}
+
+func lsGroupName(gid uint32) string {
+	if name, ok := lsGroupNames[gid]; ok {
+		return name
+	}
+	id := strconv.FormatUint(uint64(gid), 10)
+	name := id
+	if g, err := user.LookupGroupId(id); err == nil {
+		name = g.Name
+	}
+	lsGroupNames[gid] = name
+	return name
+}
+
+// lsOwner returns the count of hard links, the owner and the group of the file.
+func lsOwner(status os.FileInfo) (uint64, string, string) {
+	st, ok := status.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 1, "-", "-"
+	}
+	return uint64(st.Nlink), lsUserName(st.Uid), lsGroupName(st.Gid)
+}
+
+func (c *lsColumns) measure(folder string, status os.FileInfo) {
+	links, owner, group := lsOwner(status)
+	if w := keta(int64(links)); w > c.links {
+		c.links = w
+	}
+	if len(owner) > c.owner {
+		c.owner = len(owner)
+	}
+	if len(group) > c.group {
+		c.group = len(group)
+	}
+}
+
+// lsModeString returns the type and the permission of the file
+// as `ls -l` of Unix (for example: drwxr-xr-x)
+func lsModeString(mode os.FileMode) string {
+	s := []byte("----------")
+	switch {
+	case mode&os.ModeDir != 0:
+		s[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		s[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		s[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		s[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		s[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		s[0] = 'b'
+	}
+	const rwx = "rwxrwxrwx"
+	perm := mode.Perm()
+	for i := 0; i < 9; i++ {
+		if perm&(1<<uint(8-i)) != 0 {
+			s[i+1] = rwx[i]
+		}
+	}
+	special := func(flag os.FileMode, at int, c byte) {
+		if mode&flag == 0 {
+			return
+		}
+		if s[at] == 'x' {
+			s[at] = c
+		} else {
+			s[at] = c - 'a' + 'A'
+		}
+	}
+	special(os.ModeSetuid, 3, 's')
+	special(os.ModeSetgid, 6, 's')
+	special(os.ModeSticky, 9, 't')
+	return string(s)
+}
+
+func lsOneLong(folder string, status os.FileInfo, flag int, width *lsColumns, out io.Writer) {
+	name := status.Name()
+	if (flag & optionStripDir) > 0 {
+		name = filepath.Base(name)
+	}
+	prefix, postfix, indicator := lsDecorate(status, flag)
+
+	links, owner, group := lsOwner(status)
+	fmt.Fprintf(out, "%s %*d %-*s %-*s",
+		lsModeString(status.Mode()),
+		width.links, links,
+		width.owner, owner,
+		width.group, group)
+
+	if (flag & optionHuman) != 0 {
+		fmt.Fprintf(out, " %*s", width.size, formatByHumanize(status.Size()))
+	} else {
+		fmt.Fprintf(out, " %*d", width.size, status.Size())
+	}
+	stamp := status.ModTime()
+	now := time.Now()
+	halflastyear := now.AddDate(0, -6, 0)
+	if stamp.After(halflastyear) && !stamp.After(now) {
+		io.WriteString(out, stamp.Format(" Jan _2 15:04:05 "))
+	} else {
+		io.WriteString(out, stamp.Format(" Jan _2 2006     "))
+	}
+	io.WriteString(out, prefix)
+	io.WriteString(out, name)
+	io.WriteString(out, postfix)
+	if (flag & optionIndicator) > 0 {
+		io.WriteString(out, indicator)
+	}
+	if lsIsLink(status) {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(folder, name)
+		}
+		if linkTo, err := os.Readlink(path); err == nil {
+			fmt.Fprintf(out, " -> %s", linkTo)
+		}
+	}
+	io.WriteString(out, "\n")
+}
+
+func lsIsHidden(f os.FileInfo) bool {
+	return strings.HasPrefix(f.Name(), ".")
+}
+
+func lsIsLink(f os.FileInfo) bool {
+	return (f.Mode() & os.ModeSymlink) != 0
+}
+
+func lsIsExecutable(f os.FileInfo) bool {
+	return f.Mode().IsRegular() && (f.Mode().Perm()&0111) != 0
+}
+
+func lsIsReadOnly(f os.FileInfo) bool {
+	return (f.Mode().Perm() & 0200) == 0
+}
+
+// lsDots returns the status of `.` and `..` which os.ReadDir skips.
+func lsDots(folder string) []os.FileInfo {
+	if folder == "" {
+		folder = "."
+	}
+	result := make([]os.FileInfo, 0, 2)
+	for _, name := range []string{".", ".."} {
+		if status, err := os.Lstat(filepath.Join(folder, name)); err == nil {
+			result = append(result, &fileInfoT{name, status})
+		}
+	}
+	return result
+}
+
+func lsStatName(name string) string {
+	return name
+}
+
+// lsOwnerFields returns the fields of `ls --json` which only Unix has.
+func lsOwnerFields(status os.FileInfo) []Field {
+	links, owner, group := lsOwner(status)
+	return []Field{{"links", links}, {"owner", owner}, {"group", group}}
+}
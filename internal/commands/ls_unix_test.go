@@ -0,0 +1,40 @@
+//go:build !windows
+// +build !windows
+
+package commands_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+)
+
+func TestLsLongUnix(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, []byte("hello"), 0640); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.Chmod(target, 0640); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.Symlink("target.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err.Error())
+	}
+	var out strings.Builder
+	_, err := commands.Ls(context.Background(), []string{"ls", "-lF", dir}, &out, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("ls -lF: %s", err.Error())
+	}
+	result := out.String()
+	for _, expect := range []string{"-rw-r----- 1 ", " target.txt\n", "lrwxrwxrwx ", " link@ -> target.txt\n"} {
+		if !strings.Contains(result, expect) {
+			t.Fatalf("ls -lF: %#v not found in %#v", expect, result)
+		}
+	}
+}
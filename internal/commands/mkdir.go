@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"syscall"
@@ -32,11 +33,12 @@ func cmdMkdir(ctx context.Context, cmd Param) (int, error) {
 
 func cmdRmdir(ctx context.Context, cmd Param) (int, error) {
 	if len(cmd.Args()) <= 1 {
-		fmt.Fprintln(cmd.Err(), "Usage: rmdir [/s] [/q] DIRECTORIES...")
+		fmt.Fprintln(cmd.Err(), "Usage: rmdir [/s] [/q] [--trash] DIRECTORIES...")
 		return 0, nil
 	}
 	sOption := false
 	quiet := false
+	trash := false
 	message := "%s: Rmdir Are you sure? [Yes/No/Quit] "
 	errorcount := 0
 
@@ -48,6 +50,8 @@ func cmdRmdir(ctx context.Context, cmd Param) (int, error) {
 			message = "%s : Delete Tree. Are you sure? [Yes/No/Quit] "
 		case "/q":
 			quiet = true
+		case "--trash":
+			trash = true
 		default:
 			args = append(args, arg1)
 		}
@@ -77,7 +81,21 @@ func cmdRmdir(ctx context.Context, cmd Param) (int, error) {
 				continue
 			}
 		}
-		if sOption {
+		if trash {
+			if !stat.IsDir() {
+				fmt.Fprintf(cmd.Err(), "%s: not directory\n", arg1)
+				errorcount++
+				continue
+			}
+			// Without /s, only the empty directory is moved like rmdir.
+			if files, err1 := os.ReadDir(arg1); err1 != nil {
+				err = err1
+			} else if len(files) > 0 && !sOption {
+				err = errors.New("The directory is not empty")
+			} else {
+				err = nodos.MoveToTrash(arg1)
+			}
+		} else if sOption {
 			if !stat.IsDir() {
 				fmt.Fprintf(cmd.Err(), "%s: not directory\n", arg1)
 				errorcount++
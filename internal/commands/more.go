@@ -3,9 +3,7 @@ package commands
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
-	"math"
 	"os"
 	"strings"
 
@@ -17,10 +15,6 @@ import (
 	"github.com/nyaosorg/nyagos/internal/textwidth"
 )
 
-var bold = false
-var screenWidth int
-var screenHeight int
-
 func isTerminalIn(in io.Reader) bool {
 	f, ok := in.(*os.File)
 	if !ok {
@@ -77,91 +71,88 @@ func splitLinesWithWidth(text string, screenWidth int) (lines []string) {
 	return
 }
 
-func more(r io.Reader, cmd Param) error {
-	scanner := mbcs.NewFilter(r, mbcs.ConsoleCP())
-	count := 0
-
-	if f, ok := cmd.Out().(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
-		screenHeight = math.MaxInt32
-	}
-	for scanner.Scan() {
-		text := scanner.Text()
-		lines := splitLinesWithWidth(text, screenWidth)
-		for _, line := range lines {
-			if count+1 >= screenHeight {
-				io.WriteString(cmd.Err(), "more>")
-				ch, err := getkey()
-				if err != nil {
-					return err
-				}
-				if ch == '\x03' {
-					fmt.Fprintln(cmd.Err(), "^C")
-					return io.EOF
-				}
-				io.WriteString(cmd.Err(), "\r     \b\b\b\b\b")
-				if ch == 'q' {
-					return io.EOF
-				} else if ch == '\r' {
-					count--
-				} else {
-					count = 0
-				}
-				if bold {
-					io.WriteString(cmd.Out(), "\x1B[1m")
-				}
-			}
-			fmt.Fprintln(cmd.Out(), line)
-			count++
+// streamLines prints the readers without paging for the output
+// which is not a terminal.
+func streamLines(readers []io.Reader, out io.Writer, opt pagerOptions) error {
+	n := 0
+	for _, r := range readers {
+		scanner := mbcs.NewFilter(r, mbcs.ConsoleCP())
+		for scanner.Scan() {
+			n++
+			writeLine(out, n, scanner.Text(), opt)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
 		}
 	}
-	return scanner.Err()
+	return nil
 }
 
 func cmdMore(ctx context.Context, cmd Param) (int, error) {
-	count := 0
-
-	tty1, err := tty.Open()
-	if err != nil {
-		return 1, err
-	}
-	screenWidth, screenHeight, err = tty1.Size()
-	tty1.Close()
-	if err != nil {
-		return 1, err
-	}
+	var opt pagerOptions
+	files := []string{}
 	for _, arg1 := range cmd.Args()[1:] {
-		if arg1 == "-b" {
-			bold = true
-			continue
-		} else if arg1 == "-h" {
-			return 1, errors.New("more : Color-Unicoded more")
+		switch arg1 {
+		case "-b":
+			opt.bold = true
+		case "-N":
+			opt.number = true
+		case "-F":
+			opt.follow = true
+		case "-h":
+			return 1, errors.New("Usage: more [-b] [-N] [-F] [FILE...] : Color-Unicoded more")
+		default:
+			files = append(files, arg1)
 		}
-		r, err := os.Open(arg1)
+	}
+	done := make(chan struct{})
+	defer close(done)
+
+	readers := []io.Reader{}
+	for _, name := range files {
+		r, err := os.Open(name)
 		if err != nil {
 			return 1, err
 		}
-		if err := more(r, cmd); err != nil {
-			r.Close()
-			if err == io.EOF {
-				return 0, nil
-			}
-			return 1, err
-		}
-		r.Close()
-		count++
+		defer r.Close()
+		readers = append(readers, r)
 	}
-	if count <= 0 {
-		if isTerminalIn(cmd.In()) {
+	title := ""
+	if len(files) == 1 {
+		title = files[0]
+	}
+
+	f, ok := cmd.Out().(*os.File)
+	isTerminalOut := ok && isatty.IsTerminal(f.Fd())
+	if len(readers) <= 0 {
+		if isTerminalIn(cmd.In()) && isatty.IsTerminal(os.Stdin.Fd()) {
+			// The text typed on the terminal is not paged.
 			c, err := nodos.EnableProcessInput()
 			if err != nil {
 				return 1, err
 			}
 			defer c()
+			isTerminalOut = false
 		}
-		err := more(cmd.In(), cmd)
-		if err != nil && err != io.EOF {
-			return 1, err
+		readers = append(readers, cmd.In())
+	}
+	if opt.follow && len(files) > 0 {
+		last := len(readers) - 1
+		stop := (<-chan struct{})(done)
+		if !isTerminalOut && ctx != nil {
+			stop = ctx.Done()
 		}
+		readers[last] = &followReader{r: readers[last], done: stop}
+	}
+
+	var err error
+	if isTerminalOut {
+		err = page(ctx, readers, title, opt, cmd.Out())
+	} else {
+		err = streamLines(readers, cmd.Out(), opt)
+	}
+	if err != nil {
+		return 1, err
 	}
 	return 0, nil
 }
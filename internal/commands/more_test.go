@@ -0,0 +1,26 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMoreToFile checks that more prints without paging
+// when the output is not a terminal.
+func TestMoreToFile(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "a.txt")
+	file2 := filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(file1, []byte("\x1B[31mred\x1B[0m\nsecond"), 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.WriteFile(file2, []byte("third\n"), 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	result := testOutput(t, "more", "-N", file1, file2)
+	expect := "     1 \x1B[31mred\x1B[0m\n     2 second\n     3 third\n"
+	if result != expect {
+		t.Fatalf("more -N: %q", result)
+	}
+}
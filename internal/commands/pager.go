@@ -0,0 +1,712 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-tty"
+
+	"github.com/nyaosorg/go-windows-mbcs"
+	"github.com/nyaosorg/nyagos/internal/textwidth"
+)
+
+// the width of the line number given by -N: "%6d "
+const pagerNumberWidth = 7
+
+const pagerHelp = "SPC/b:page j/k:line g/G:top/end /?:search n/N:next NUM:jump l:number F:follow q:quit"
+
+type pagerOptions struct {
+	bold   bool
+	number bool
+	follow bool
+}
+
+// pager is the viewer of `more` which can scroll back and search.
+// The lines are read on another goroutine, so the first page is shown
+// before the whole input is read.
+type pager struct {
+	pagerOptions
+	tty   *tty.TTY
+	out   io.Writer
+	title string
+
+	mu     sync.Mutex
+	lines  []string
+	eof    bool
+	err    error
+	update chan struct{}
+
+	top       int
+	bottom    int // the last line drawn entirely
+	pattern   *regexp.Regexp
+	backward  bool
+	matchLine int
+	message   string
+}
+
+func newPager(opt pagerOptions, title string) *pager {
+	return &pager{
+		pagerOptions: opt,
+		title:        title,
+		update:       make(chan struct{}, 1),
+		matchLine:    -1,
+	}
+}
+
+func isAnsiTerminator(c rune) bool {
+	return ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+}
+
+// expandTabs replaces tabs with spaces not counting escape sequences,
+// because the width of tab can not be known by splitLinesWithWidth.
+func expandTabs(s string) string {
+	if strings.IndexByte(s, '\t') < 0 {
+		return s
+	}
+	var buffer strings.Builder
+	w := 0
+	esc := false
+	for _, c := range s {
+		switch {
+		case esc:
+			esc = !isAnsiTerminator(c)
+		case c == '\x1B':
+			esc = true
+		case c == '\t':
+			n := 8 - w%8
+			buffer.WriteString(strings.Repeat(" ", n))
+			w += n
+			continue
+		default:
+			w += textwidth.RuneWidth(c)
+		}
+		buffer.WriteRune(c)
+	}
+	return buffer.String()
+}
+
+// stripAnsi returns the text without escape sequences and
+// the position in s of each byte of the text.
+func stripAnsi(s string) (string, []int) {
+	var buffer strings.Builder
+	pos := make([]int, 0, len(s))
+	esc := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if esc {
+			esc = !isAnsiTerminator(rune(c))
+			continue
+		}
+		if c == '\x1B' {
+			esc = true
+			continue
+		}
+		buffer.WriteByte(c)
+		pos = append(pos, i)
+	}
+	return buffer.String(), pos
+}
+
+// highlight shows the matches of rx with the reverse video
+// keeping the colors of the line.
+func highlight(line string, rx *regexp.Regexp) string {
+	plain, pos := stripAnsi(line)
+	matches := rx.FindAllStringIndex(plain, -1)
+	if len(matches) <= 0 {
+		return line
+	}
+	var buffer strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m[0] >= m[1] {
+			continue
+		}
+		start := pos[m[0]]
+		end := pos[m[1]-1] + 1
+		buffer.WriteString(line[last:start])
+		buffer.WriteString("\x1B[7m")
+		buffer.WriteString(line[start:end])
+		buffer.WriteString("\x1B[27m")
+		last = end
+	}
+	buffer.WriteString(line[last:])
+	return buffer.String()
+}
+
+var sgrPattern = regexp.MustCompile("\x1B\\[[0-9;]*m")
+
+// carrySGR repeats the colors set in the previous rows
+// at the head of each wrapped row, so that a row can be drawn alone.
+func carrySGR(rows []string) []string {
+	active := ""
+	for i, row := range rows {
+		if active != "" {
+			rows[i] = active + row
+		}
+		for _, seq := range sgrPattern.FindAllString(row, -1) {
+			if seq == "\x1B[m" || seq == "\x1B[0m" {
+				active = ""
+			} else if strings.HasPrefix(seq, "\x1B[0;") {
+				active = seq
+			} else {
+				active += seq
+			}
+		}
+	}
+	return rows
+}
+
+// readKey reads a key and the following escape sequence.
+// It returns "" for the events which are not keys (and NUL).
+func readKey(t *tty.TTY) (string, error) {
+	var key strings.Builder
+	for {
+		c, err := t.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		if c == 0 {
+			if key.Len() <= 0 && !t.Buffered() {
+				return "", nil
+			}
+			continue
+		}
+		key.WriteRune(c)
+		if !t.Buffered() {
+			return key.String(), nil
+		}
+	}
+}
+
+// followReader keeps reading the growing file after EOF
+// until done is closed. The file truncated is read from the top again.
+type followReader struct {
+	r    io.Reader
+	done <-chan struct{}
+}
+
+func (f *followReader) Read(b []byte) (int, error) {
+	for {
+		n, err := f.r.Read(b)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		if fd, ok := f.r.(*os.File); ok {
+			if pos, err := fd.Seek(0, io.SeekCurrent); err == nil {
+				if stat, err := fd.Stat(); err == nil && stat.Size() < pos {
+					fd.Seek(0, io.SeekStart)
+				}
+			}
+		}
+		select {
+		case <-f.done:
+			return 0, io.EOF
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+func (p *pager) notify() {
+	select {
+	case p.update <- struct{}{}:
+	default:
+	}
+}
+
+// read appends the lines of the readers until EOF.
+func (p *pager) read(readers []io.Reader) {
+	var err error
+	for _, r := range readers {
+		scanner := mbcs.NewFilter(r, mbcs.ConsoleCP())
+		for scanner.Scan() {
+			line := expandTabs(scanner.Text())
+			p.mu.Lock()
+			p.lines = append(p.lines, line)
+			p.mu.Unlock()
+			p.notify()
+		}
+		if err = scanner.Err(); err != nil {
+			break
+		}
+	}
+	p.mu.Lock()
+	p.eof = true
+	p.err = err
+	p.mu.Unlock()
+	p.notify()
+}
+
+// snapshot returns the lines read until now.
+// The slice can be read without the lock because lines are only appended.
+func (p *pager) snapshot() ([]string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lines, p.eof
+}
+
+func (p *pager) size() (int, int) {
+	width, height, err := p.tty.Size()
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 25
+	}
+	if height < 2 {
+		height = 2
+	}
+	return width, height
+}
+
+func (p *pager) textWidth(width int) int {
+	if p.number {
+		width -= pagerNumberWidth
+	}
+	if width < 2 {
+		width = 2
+	}
+	return width
+}
+
+// rows returns the rows of the line wrapped for the screen.
+func (p *pager) rows(line string, width int) []string {
+	if p.pattern != nil {
+		line = highlight(line, p.pattern)
+	}
+	return carrySGR(splitLinesWithWidth(line, width))
+}
+
+// countRows returns the number of rows of lines[from:] up to limit.
+func countRows(lines []string, from, width, limit int) int {
+	n := 0
+	for i := from; i < len(lines) && n <= limit; i++ {
+		n += len(splitLinesWithWidth(lines[i], width))
+	}
+	return n
+}
+
+// maxTop returns the top line with which the last line is shown
+// at the bottom of the screen.
+func (p *pager) maxTop(lines []string) int {
+	width, height := p.size()
+	width = p.textWidth(width)
+	rows := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		rows += len(splitLinesWithWidth(lines[i], width))
+		if rows > height-1 {
+			if i+1 < len(lines) {
+				return i + 1
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+func (p *pager) setTop(top int) {
+	lines, _ := p.snapshot()
+	if m := p.maxTop(lines); top > m {
+		top = m
+	}
+	if top < 0 {
+		top = 0
+	}
+	p.top = top
+}
+
+// scrollUp moves the top line up by the rows.
+func (p *pager) scrollUp(rows int) {
+	lines, _ := p.snapshot()
+	width, _ := p.size()
+	width = p.textWidth(width)
+	top := p.top
+	for top > 0 {
+		rows -= len(splitLinesWithWidth(lines[top-1], width))
+		if rows < 0 {
+			break
+		}
+		top--
+	}
+	if top == p.top && top > 0 {
+		top--
+	}
+	p.setTop(top)
+}
+
+func (p *pager) status(lines []string, eof bool) string {
+	if p.message != "" {
+		return p.message
+	}
+	if p.follow {
+		return "Waiting for data... (press any key to stop)"
+	}
+	var buffer strings.Builder
+	if p.title != "" {
+		buffer.WriteString(p.title)
+		buffer.WriteByte(' ')
+	}
+	if len(lines) > 0 {
+		fmt.Fprintf(&buffer, "lines %d-%d", p.top+1, p.bottom+1)
+		if eof {
+			fmt.Fprintf(&buffer, "/%d %d%%", len(lines), (p.bottom+1)*100/len(lines))
+		}
+	}
+	if eof && p.bottom >= len(lines)-1 {
+		buffer.WriteString(" (END)")
+	}
+	return buffer.String()
+}
+
+func (p *pager) draw() {
+	width, height := p.size()
+	lines, eof := p.snapshot()
+	textWidth := p.textWidth(width)
+	page := height - 1
+
+	var buffer strings.Builder
+	buffer.WriteString("\x1B[H")
+	n := 0
+	p.bottom = p.top - 1
+	for i := p.top; i < len(lines) && n < page; i++ {
+		rows := p.rows(lines[i], textWidth)
+		for j, row := range rows {
+			if n >= page {
+				rows = nil
+				break
+			}
+			if p.number {
+				if j == 0 {
+					fmt.Fprintf(&buffer, "%*d ", pagerNumberWidth-1, i+1)
+				} else {
+					buffer.WriteString(strings.Repeat(" ", pagerNumberWidth))
+				}
+			}
+			if p.bold {
+				buffer.WriteString("\x1B[1m")
+			}
+			buffer.WriteString(row)
+			buffer.WriteString("\x1B[0m\x1B[K\r\n")
+			n++
+		}
+		if rows != nil {
+			p.bottom = i
+		}
+	}
+	for ; n < page; n++ {
+		buffer.WriteString("~\x1B[K\r\n")
+	}
+	buffer.WriteString("\x1B[7m")
+	buffer.WriteString(cutWidth(p.status(lines, eof), width-1))
+	buffer.WriteString("\x1B[0m\x1B[K")
+	io.WriteString(p.out, buffer.String())
+	p.message = ""
+}
+
+// prompt reads a text on the bottom line.
+// It returns false when canceled by ESC or Ctrl-C.
+func (p *pager) prompt(prefix, text string) (string, bool) {
+	_, height := p.size()
+	for {
+		fmt.Fprintf(p.out, "\x1B[%d;1H%s%s\x1B[K", height, prefix, text)
+		key, err := readKey(p.tty)
+		if err != nil {
+			return "", false
+		}
+		switch key {
+		case "\r", "\n":
+			return text, true
+		case "\x1B", "\x03", "\x07":
+			return "", false
+		case "\b", "\x7F":
+			if text == "" {
+				return "", false
+			}
+			r := []rune(text)
+			text = string(r[:len(r)-1])
+		default:
+			if key[0] >= ' ' && key[0] != '\x7F' {
+				text += key
+			}
+		}
+	}
+}
+
+// search moves to the next line matching the pattern.
+// The first search starts from the screen, and the next one
+// from the line matched before while it is on the screen.
+// The line found is shown at the top.
+func (p *pager) search(backward, again bool) {
+	if p.pattern == nil {
+		p.message = "No previous pattern"
+		return
+	}
+	lines, _ := p.snapshot()
+	visible := p.matchLine >= p.top && p.matchLine <= p.bottom
+	found := -1
+	if backward {
+		from := p.bottom
+		if again {
+			from = p.top - 1
+			if visible {
+				from = p.matchLine - 1
+			}
+		}
+		for i := from; i >= 0 && i < len(lines); i-- {
+			if plain, _ := stripAnsi(lines[i]); p.pattern.MatchString(plain) {
+				found = i
+				break
+			}
+		}
+	} else {
+		from := p.top
+		if again {
+			from = p.top + 1
+			if visible {
+				from = p.matchLine + 1
+			}
+		}
+		if from < 0 {
+			from = 0
+		}
+		for i := from; i < len(lines); i++ {
+			if plain, _ := stripAnsi(lines[i]); p.pattern.MatchString(plain) {
+				found = i
+				break
+			}
+		}
+	}
+	if found < 0 {
+		p.message = "Pattern not found"
+		return
+	}
+	p.matchLine = found
+	p.setTop(found)
+}
+
+// command does the key and returns true to quit.
+func (p *pager) command(key string) bool {
+	lines, _ := p.snapshot()
+	_, height := p.size()
+	page := height - 1
+	if p.follow {
+		p.follow = false
+		return key == "q" || key == "Q" || key == "\x03"
+	}
+	switch key {
+	case "q", "Q", "\x03":
+		return true
+	case " ", "f", "\x06", "\x16", "\x1B[6~":
+		if p.bottom >= p.top {
+			p.setTop(p.bottom + 1)
+		} else {
+			p.setTop(p.top + 1)
+		}
+	case "b", "\x02", "\x1B[5~":
+		p.scrollUp(page)
+	case "\r", "\n", "j", "e", "\x0E", "\x1B[B":
+		p.setTop(p.top + 1)
+	case "k", "y", "\x10", "\x1B[A":
+		p.scrollUp(0)
+	case "d", "\x04":
+		p.setTop(p.top + page/2)
+	case "u", "\x15":
+		p.scrollUp(page / 2)
+	case "g", "<", "\x1B[H", "\x1B[1~":
+		p.setTop(0)
+	case "G", ">", "\x1B[F", "\x1B[4~":
+		p.setTop(len(lines))
+	case "/", "?":
+		text, ok := p.prompt(key, "")
+		if !ok {
+			break
+		}
+		if text == "" {
+			p.search(key == "?", true)
+			break
+		}
+		rx, err := regexp.Compile(text)
+		if err != nil {
+			p.message = err.Error()
+			break
+		}
+		p.pattern = rx
+		p.backward = key == "?"
+		p.matchLine = -1
+		p.search(p.backward, false)
+	case "n":
+		p.search(p.backward, true)
+	case "N":
+		p.search(!p.backward, true)
+	case "l":
+		p.number = !p.number
+	case "F":
+		p.follow = true
+		p.setTop(len(lines))
+	case "h", "H":
+		p.message = pagerHelp
+	case ":", "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		text := key
+		if key == ":" {
+			text = ""
+		}
+		text, ok := p.prompt(":", text)
+		if !ok || text == "" {
+			break
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil || n <= 0 {
+			p.message = fmt.Sprintf("%s: invalid line number", text)
+			break
+		}
+		p.setTop(n - 1)
+	}
+	return false
+}
+
+func (p *pager) run(ctx context.Context) error {
+	type keyEvent struct {
+		key string
+		err error
+	}
+	// A key is read on another goroutine to watch the input together,
+	// but the next key is requested only after the previous one is done.
+	// The goroutine waiting for a key is stopped at the exit
+	// not to steal the next key from the command-line.
+	var pending chan keyEvent
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		if pending != nil {
+			<-pending
+		}
+	}()
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	var redraw <-chan time.Time
+	p.draw()
+	for {
+		if pending == nil {
+			pending = make(chan keyEvent, 1)
+			go func(ch chan keyEvent) {
+				if !waitKey(p.tty, stop) {
+					ch <- keyEvent{}
+					return
+				}
+				key, err := readKey(p.tty)
+				ch <- keyEvent{key: key, err: err}
+			}(pending)
+		}
+		select {
+		case ev := <-pending:
+			pending = nil
+			if ev.err != nil {
+				return ev.err
+			}
+			if ev.key == "" {
+				p.draw()
+				break
+			}
+			if p.command(ev.key) {
+				return nil
+			}
+			p.draw()
+		case <-p.update:
+			if redraw == nil {
+				redraw = time.After(50 * time.Millisecond)
+			}
+		case <-done:
+			return nil
+		case <-redraw:
+			redraw = nil
+			if p.follow {
+				lines, _ := p.snapshot()
+				p.setTop(len(lines))
+			}
+			p.draw()
+		}
+	}
+}
+
+// waitFirstPage waits until the input fills the screen, reaches EOF
+// or a second passes, and returns true when the whole input fits the screen.
+func (p *pager) waitFirstPage() bool {
+	width, height := p.size()
+	width = p.textWidth(width)
+	timeout := time.After(time.Second)
+	for {
+		lines, eof := p.snapshot()
+		if countRows(lines, 0, width, height) >= height {
+			return false
+		}
+		if eof {
+			return !p.follow
+		}
+		select {
+		case <-p.update:
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+// page shows the readers on the terminal. The text which fits the screen
+// is just printed like `less -F`.
+func page(ctx context.Context, readers []io.Reader, title string, opt pagerOptions, out io.Writer) error {
+	tty1, err := tty.Open()
+	if err != nil {
+		return err
+	}
+	defer tty1.Close()
+
+	p := newPager(opt, title)
+	p.tty = tty1
+	p.out = tty1.Output()
+	go p.read(readers)
+
+	if p.waitFirstPage() {
+		lines, _ := p.snapshot()
+		writeLines(out, lines, opt)
+		return p.err
+	}
+
+	restore, err := tty1.Raw()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	io.WriteString(p.out, "\x1B[?1049h")
+	defer io.WriteString(p.out, "\x1B[?1049l")
+	if p.follow {
+		lines, _ := p.snapshot()
+		p.setTop(len(lines))
+	}
+	if err := p.run(ctx); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func writeLines(out io.Writer, lines []string, opt pagerOptions) {
+	for i, line := range lines {
+		writeLine(out, i+1, line, opt)
+	}
+}
+
+func writeLine(out io.Writer, n int, line string, opt pagerOptions) {
+	if opt.number {
+		fmt.Fprintf(out, "%*d ", pagerNumberWidth-1, n)
+	}
+	if opt.bold {
+		fmt.Fprintf(out, "\x1B[1m%s\x1B[0m\n", line)
+	} else {
+		fmt.Fprintln(out, line)
+	}
+}
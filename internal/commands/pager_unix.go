@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"github.com/mattn/go-tty"
+	"golang.org/x/sys/unix"
+)
+
+// waitKey waits until a key is typed or stop is closed,
+// and returns false for the latter.
+func waitKey(t *tty.TTY, stop <-chan struct{}) bool {
+	fds := []unix.PollFd{{Fd: int32(t.Input().Fd()), Events: unix.POLLIN}}
+	for !t.Buffered() {
+		select {
+		case <-stop:
+			return false
+		default:
+		}
+		if n, err := unix.Poll(fds, 100); n > 0 || (err != nil && err != unix.EINTR) {
+			return true
+		}
+	}
+	return true
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"github.com/mattn/go-tty"
+	"golang.org/x/sys/windows"
+)
+
+// waitKey waits until a key is typed or stop is closed,
+// and returns false for the latter.
+func waitKey(t *tty.TTY, stop <-chan struct{}) bool {
+	handle := windows.Handle(t.Input().Fd())
+	for !t.Buffered() {
+		select {
+		case <-stop:
+			return false
+		default:
+		}
+		if rc, err := windows.WaitForSingleObject(handle, 100); rc == windows.WAIT_OBJECT_0 || err != nil {
+			return true
+		}
+	}
+	return true
+}
@@ -2,26 +2,268 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mitchellh/go-ps"
 )
 
+// processDetail has the columns of `ps -l` which are available only on Linux
+type processDetail struct {
+	cmdline string
+	user    string
+	rss     int64
+	cpuTime time.Duration
+	start   time.Time
+}
+
+type processT struct {
+	pid    int
+	ppid   int
+	name   string
+	depth  int
+	detail *processDetail
+	loaded bool
+}
+
+// getDetail reads the detail of the process at the first call only,
+// because it reads the files of /proc for each process.
+func (p *processT) getDetail() *processDetail {
+	if !p.loaded {
+		p.detail = getProcessDetail(p.pid)
+		p.loaded = true
+	}
+	return p.detail
+}
+
+type psOptions struct {
+	tree    bool
+	long    bool
+	names   []string
+	pattern *regexp.Regexp
+}
+
+const psUsage = "Usage: ps [-t] [-l] [-r REGEXP] [NAME...]"
+
+func parsePsOptions(args []string) (*psOptions, error) {
+	opt := &psOptions{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-t":
+			opt.tree = true
+		case "-l":
+			opt.long = true
+		case "-tl", "-lt":
+			opt.tree = true
+			opt.long = true
+		case "-r":
+			if i+1 >= len(args) {
+				return nil, errors.New(psUsage)
+			}
+			i++
+			rx, err := regexp.Compile(args[i])
+			if err != nil {
+				return nil, err
+			}
+			opt.pattern = rx
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, errors.New(psUsage)
+			}
+			opt.names = append(opt.names, strings.ToLower(arg))
+		}
+	}
+	return opt, nil
+}
+
+func (opt *psOptions) match(p *processT) bool {
+	if len(opt.names) <= 0 && opt.pattern == nil {
+		return true
+	}
+	name := strings.ToLower(p.name)
+	for _, s := range opt.names {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	if opt.pattern != nil {
+		if opt.pattern.MatchString(p.name) {
+			return true
+		}
+		if d := p.getDetail(); d != nil && opt.pattern.MatchString(d.cmdline) {
+			return true
+		}
+	}
+	return false
+}
+
+// listProcesses returns the processes matching the options.
+// With -t, they are ordered as the tree and the children of the
+// matched processes are listed too.
+func listProcesses(opt *psOptions) ([]*processT, error) {
+	list, err := ps.Processes()
+	if err != nil {
+		return nil, err
+	}
+	all := make([]*processT, 0, len(list))
+	for _, p := range list {
+		all = append(all, &processT{
+			pid:  p.Pid(),
+			ppid: p.PPid(),
+			name: p.Executable(),
+		})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].pid < all[j].pid })
+
+	if !opt.tree {
+		result := make([]*processT, 0, len(all))
+		for _, p := range all {
+			if opt.match(p) {
+				result = append(result, p)
+			}
+		}
+		return result, nil
+	}
+
+	children := map[int][]*processT{}
+	exists := map[int]bool{}
+	for _, p := range all {
+		exists[p.pid] = true
+	}
+	roots := []*processT{}
+	for _, p := range all {
+		if p.ppid == p.pid || !exists[p.ppid] {
+			roots = append(roots, p)
+		} else {
+			children[p.ppid] = append(children[p.ppid], p)
+		}
+	}
+	result := make([]*processT, 0, len(all))
+	var walk func(p *processT, depth int, matched bool)
+	walk = func(p *processT, depth int, matched bool) {
+		matched = matched || opt.match(p)
+		if matched {
+			p.depth = depth
+			result = append(result, p)
+			depth++
+		}
+		for _, c := range children[p.pid] {
+			walk(c, depth, matched)
+		}
+	}
+	for _, p := range roots {
+		walk(p, 0, false)
+	}
+	return result, nil
+}
+
+func formatCPUTime(d time.Duration) string {
+	s := int64(d / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, (s/60)%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+func formatStartTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	now := time.Now()
+	if t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan02")
+}
+
+func printProcess(out io.Writer, p *processT, opt *psOptions, self int) {
+	fmt.Fprintf(out, "%6d %6d", p.pid, p.ppid)
+	command := p.name
+	if opt.long {
+		if d := p.getDetail(); d != nil {
+			fmt.Fprintf(out, " %-8s %6s %8s %5s",
+				d.user, formatByHumanize(d.rss), formatCPUTime(d.cpuTime), formatStartTime(d.start))
+			if d.cmdline != "" {
+				command = d.cmdline
+			}
+		} else {
+			fmt.Fprintf(out, " %-8s %6s %8s %5s", "-", "-", "-", "-")
+		}
+	}
+	if opt.tree && p.depth > 0 {
+		command = strings.Repeat("  ", p.depth-1) + "\\_ " + command
+	}
+	fmt.Fprintf(out, " %s", command)
+	if self == p.pid {
+		fmt.Fprintln(out, " [self]")
+	} else {
+		fmt.Fprintln(out)
+	}
+}
+
 func cmdPs(ctx context.Context, cmd Param) (int, error) {
-	processes, err := ps.Processes()
+	opt, err := parsePsOptions(cmd.Args()[1:])
 	if err != nil {
 		return 1, err
 	}
-	fmt.Fprintf(cmd.Out(), "%6s %6s %s\n", "PID", "PPID", "COMMAND")
+	processes, err := listProcesses(opt)
+	if err != nil {
+		return 1, err
+	}
+	if opt.long {
+		fmt.Fprintf(cmd.Out(), "%6s %6s %-8s %6s %8s %5s %s\n",
+			"PID", "PPID", "USER", "RSS", "TIME", "START", "COMMAND")
+	} else {
+		fmt.Fprintf(cmd.Out(), "%6s %6s %s\n", "PID", "PPID", "COMMAND")
+	}
 	self := os.Getpid()
 	for _, p := range processes {
-		fmt.Fprintf(cmd.Out(), "%6d %6d %s", p.Pid(), p.PPid(), p.Executable())
-		if self == p.Pid() {
-			fmt.Fprintln(cmd.Out(), " [self]")
-		} else {
-			fmt.Fprintln(cmd.Out())
-		}
+		printProcess(cmd.Out(), p, opt, self)
 	}
 	return 0, nil
 }
+
+func psRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	opt, err := parsePsOptions(cmd.Args()[1:])
+	if err != nil {
+		return nil, err
+	}
+	processes, err := listProcesses(opt)
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	records := make([]Record, 0, len(processes))
+	for _, p := range processes {
+		r := Record{
+			{"pid", p.pid},
+			{"ppid", p.ppid},
+			{"command", p.name},
+			{"self", self == p.pid},
+		}
+		if opt.tree {
+			r = append(r, Field{"depth", p.depth})
+		}
+		if d := p.getDetail(); d != nil {
+			start := ""
+			if !d.start.IsZero() {
+				start = d.start.Format(time.RFC3339)
+			}
+			r = append(r,
+				Field{"cmdline", d.cmdline},
+				Field{"user", d.user},
+				Field{"rss", d.rss},
+				Field{"cputime", d.cpuTime.Seconds()},
+				Field{"start", start})
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
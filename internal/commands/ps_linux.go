@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clock ticks per second for /proc/PID/stat (sysconf(_SC_CLK_TCK) on Linux)
+const procClockTick = 100
+
+var (
+	procBootTime     time.Time
+	procBootTimeOnce sync.Once
+)
+
+func getBootTime() time.Time {
+	procBootTimeOnce.Do(func() {
+		fd, err := os.Open("/proc/stat")
+		if err != nil {
+			return
+		}
+		defer fd.Close()
+		sc := bufio.NewScanner(fd)
+		for sc.Scan() {
+			if fields := strings.Fields(sc.Text()); len(fields) >= 2 && fields[0] == "btime" {
+				if sec, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+					procBootTime = time.Unix(sec, 0)
+				}
+				return
+			}
+		}
+	})
+	return procBootTime
+}
+
+// getProcessDetail reads /proc/PID for the columns of `ps -l`
+func getProcessDetail(pid int) *processDetail {
+	dir := "/proc/" + strconv.Itoa(pid) + "/"
+	stat, err := os.ReadFile(dir + "stat")
+	if err != nil {
+		return nil
+	}
+	d := &processDetail{user: "-"}
+
+	// The command name in the parentheses may have spaces.
+	if pos := strings.LastIndexByte(string(stat), ')'); pos >= 0 {
+		fields := strings.Fields(string(stat[pos+1:]))
+		// fields[0] is the 3rd field: state
+		if len(fields) > 19 {
+			utime, _ := strconv.ParseInt(fields[11], 10, 64)
+			stime, _ := strconv.ParseInt(fields[12], 10, 64)
+			d.cpuTime = time.Duration(utime+stime) * time.Second / procClockTick
+			if start, err := strconv.ParseInt(fields[19], 10, 64); err == nil {
+				if boot := getBootTime(); !boot.IsZero() {
+					d.start = boot.Add(time.Duration(start) * time.Second / procClockTick)
+				}
+			}
+		}
+	}
+	if status, err := os.ReadFile(dir + "status"); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "Uid:":
+				if uid, err := strconv.ParseUint(fields[1], 10, 32); err == nil {
+					d.user = lsUserName(uint32(uid))
+				}
+			case "VmRSS:":
+				if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+					d.rss = kb * 1024
+				}
+			}
+		}
+	}
+	if cmdline, err := os.ReadFile(dir + "cmdline"); err == nil {
+		d.cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	return d
+}
+
+// isZombie returns true when the process exited but is not waited yet.
+func isZombie(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	pos := strings.LastIndexByte(string(stat), ')')
+	return pos >= 0 && strings.HasPrefix(string(stat[pos+1:]), " Z")
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package commands
+
+// getProcessDetail returns nil because the columns of `ps -l`
+// are available only on Linux.
+func getProcessDetail(pid int) *processDetail {
+	return nil
+}
+
+func isZombie(pid int) bool {
+	return false
+}
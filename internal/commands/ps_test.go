@@ -0,0 +1,24 @@
+package commands_test
+
+import (
+	"testing"
+)
+
+func TestPsFilter(t *testing.T) {
+	found := false
+	for _, r := range testRecords(t, "ps", "-t", "-r", `^commands\.test`) {
+		m := r.Map()
+		if m["self"] == true {
+			found = true
+			if _, ok := m["depth"]; !ok {
+				t.Fatalf("ps -t: no depth: %#v", m)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("ps -r: the test process is not found")
+	}
+	if records := testRecords(t, "ps", "no-such-process-name"); len(records) != 0 {
+		t.Fatalf("ps NAME: %#v", records)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/nyaosorg/nyagos/internal/nodos"
 )
@@ -17,15 +19,60 @@ const (
 	getwdFail  = 3
 )
 
-func cmdDirs(ctx context.Context, cmd Param) (int, error) {
+// dirsList returns the current directory and the directory stack from the top,
+// which are numbered from 0 by `dirs -v`.
+func dirsList() ([]string, error) {
 	wd, err := os.Getwd()
 	if err != nil {
-		return getwdFail, err
+		return nil, err
 	}
-	io.WriteString(cmd.Out(), wd)
+	list := make([]string, 0, len(dirstack)+1)
+	list = append(list, wd)
 	for i := len(dirstack) - 1; i >= 0; i-- {
-		fmt.Fprint(cmd.Out(), " ", dirstack[i])
+		list = append(list, dirstack[i])
+	}
+	return list, nil
+}
+
+// setDirsList is the reverse of dirsList except for the current directory.
+func setDirsList(list []string) {
+	dirstack = dirstack[:0]
+	for i := len(list) - 1; i >= 1; i-- {
+		dirstack = append(dirstack, list[i])
+	}
+}
+
+// dirsIndex converts `+N` (from the left of `dirs`) and `-N` (from the right)
+// to the index of dirsList. ok is false when arg is not such a form.
+func dirsIndex(arg string, size int) (index int, ok bool, err error) {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(arg[1:])
+	if err != nil {
+		return 0, false, nil
+	}
+	if arg[0] == '-' {
+		n = size - 1 - n
+	}
+	if n < 0 || n >= size {
+		return 0, true, fmt.Errorf("%s: directory stack index out of range", arg)
+	}
+	return n, true, nil
+}
+
+func cmdDirs(ctx context.Context, cmd Param) (int, error) {
+	list, err := dirsList()
+	if err != nil {
+		return getwdFail, err
+	}
+	if len(cmd.Args()) >= 2 && cmd.Arg(1) == "-v" {
+		for i, dir := range list {
+			fmt.Fprintf(cmd.Out(), "%2d  %s\n", i, dir)
+		}
+		return 0, nil
 	}
+	io.WriteString(cmd.Out(), strings.Join(list, " "))
 	fmt.Fprintln(cmd.Out())
 	return 0, nil
 }
@@ -34,11 +81,30 @@ func cmdPopd(ctx context.Context, cmd Param) (int, error) {
 	if len(dirstack) <= 0 {
 		return noDirStack, errors.New("popd: directory stack empty")
 	}
+	if len(cmd.Args()) >= 2 {
+		list, err := dirsList()
+		if err != nil {
+			return getwdFail, err
+		}
+		n, ok, err := dirsIndex(cmd.Arg(1), len(list))
+		if err != nil {
+			return noDirStack, fmt.Errorf("popd: %w", err)
+		}
+		if !ok {
+			return noDirStack, fmt.Errorf("popd: %s: invalid argument", cmd.Arg(1))
+		}
+		if n > 0 {
+			// remove the entry without changing the current directory
+			setDirsList(append(list[:n], list[n+1:]...))
+			return cmdDirs(ctx, cmd)
+		}
+	}
 	err := nodos.Chdir(dirstack[len(dirstack)-1])
 	if err != nil {
 		return errnoChdirFail, err
 	}
 	dirstack = dirstack[:len(dirstack)-1]
+	visitDirectory(cmd)
 	return cmdDirs(ctx, cmd)
 }
 
@@ -48,11 +114,38 @@ func cmdPushd(ctx context.Context, cmd Param) (int, error) {
 		return getwdFail, err
 	}
 	if len(cmd.Args()) >= 2 {
+		list, err := dirsList()
+		if err != nil {
+			return getwdFail, err
+		}
+		n, ok, err := dirsIndex(cmd.Arg(1), len(list))
+		if err != nil {
+			return noDirStack, fmt.Errorf("pushd: %w", err)
+		}
+		if ok {
+			// rotate the stack so that the N-th directory becomes the top
+			list = append(list[n:], list[:n]...)
+			if err := nodos.Chdir(list[0]); err != nil {
+				return errnoChdirFail, err
+			}
+			visitDirectory(cmd)
+			setDirsList(list)
+			return cmdDirs(ctx, cmd)
+		}
+		dir := cmd.Arg(1)
+		if dir == "-i" {
+			dir, err = pickDirectory(cmd.Args()[2:])
+			if err != nil || dir == "" {
+				return errnoChdirFail, err
+			}
+		}
 		dirstack = append(dirstack, wd)
-		_, err := cmdCdSub(cmd.Arg(1))
+		_, err = cmdCdSub(dir)
 		if err != nil {
+			dirstack = dirstack[:len(dirstack)-1]
 			return errnoChdirFail, err
 		}
+		visitDirectory(cmd)
 	} else {
 		if len(dirstack) <= 0 {
 			return noDirStack, errors.New("pushd: directory stack empty")
@@ -62,6 +155,19 @@ func cmdPushd(ctx context.Context, cmd Param) (int, error) {
 			return errnoChdirFail, err
 		}
 		dirstack[len(dirstack)-1] = wd
+		visitDirectory(cmd)
 	}
 	return cmdDirs(ctx, cmd)
 }
+
+func dirsRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	list, err := dirsList()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(list))
+	for i, dir := range list {
+		records = append(records, Record{{"index", i}, {"path", dir}})
+	}
+	return records, nil
+}
@@ -0,0 +1,64 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testDirs(t *testing.T) []string {
+	t.Helper()
+	result := []string{}
+	for _, r := range testRecords(t, "dirs") {
+		result = append(result, r.Map()["path"].(string))
+	}
+	return result
+}
+
+func TestPushdRotate(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.Chdir(wd)
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	dirs := []string{}
+	for _, name := range []string{"a", "b", "c"} {
+		d := filepath.Join(dir, name)
+		if err := os.Mkdir(d, 0777); err != nil {
+			t.Fatal(err.Error())
+		}
+		dirs = append(dirs, d)
+	}
+	testExec(t, "cd", dir)
+	for _, d := range dirs {
+		testExec(t, "pushd", d)
+	}
+	defer func() {
+		for len(testDirs(t)) > 1 {
+			testExec(t, "popd")
+		}
+	}()
+	// c b a dir
+	testExec(t, "pushd", "+2")
+	if got := testDirs(t); len(got) != 4 || got[0] != dirs[0] || got[1] != dir || got[3] != dirs[1] {
+		t.Fatalf("pushd +2: %v", got)
+	}
+	if wd, _ := os.Getwd(); wd != dirs[0] {
+		t.Fatalf("pushd +2: current directory is %s", wd)
+	}
+	// a dir c b
+	testExec(t, "pushd", "-0")
+	if got := testDirs(t); got[0] != dirs[1] || got[1] != dirs[0] {
+		t.Fatalf("pushd -0: %v", got)
+	}
+	// b a dir c
+	testExec(t, "popd", "+2")
+	if got := testDirs(t); len(got) != 3 || got[0] != dirs[1] || got[2] != dirs[2] {
+		t.Fatalf("popd +2: %v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nyaosorg/nyagos/internal/go-ignorecase-sorted"
+)
+
+// Field is a pair of the name and the value in Record
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Record is one row of the structured output of built-in commands.
+// The order of fields is kept on JSON Lines and TSV.
+type Record []Field
+
+// Map returns the fields as a map (for Lua tables)
+func (r Record) Map() map[string]any {
+	m := make(map[string]any, len(r))
+	for _, f := range r {
+		m[f.Key] = f.Value
+	}
+	return m
+}
+
+const (
+	formatText = iota
+	formatJSON
+	formatTSV
+)
+
+// recordSubcommands are the subcommands which `--json` and `--tsv`
+// may follow like `trash list --json`.
+var recordSubcommands = map[string]string{
+	"trash": "list",
+}
+
+// cutFormatOption removes `--json` and `--tsv` from args
+// and returns the format selected by them.
+// They are recognized only before the first argument which is not an option
+// (except the subcommand), so that the arguments for other commands
+// like `env FOO=1 cmd --json` and `alias x=cmd --json` are not taken.
+func cutFormatOption(args []string) (int, []string) {
+	format := formatText
+	if len(args) <= 0 {
+		return format, args
+	}
+	subcommand, hasSubcommand := recordSubcommands[strings.ToLower(args[0])]
+	result := make([]string, 1, len(args))
+	result[0] = args[0]
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if strings.EqualFold(arg, "--json") {
+			format = formatJSON
+			continue
+		}
+		if strings.EqualFold(arg, "--tsv") {
+			format = formatTSV
+			continue
+		}
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			if i != 1 || !hasSubcommand || !strings.EqualFold(arg, subcommand) {
+				result = append(result, args[i:]...)
+				break
+			}
+		}
+		result = append(result, arg)
+	}
+	return format, result
+}
+
+func writeJSONLine(w io.Writer, r Record) error {
+	var buffer strings.Builder
+	buffer.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, err := json.Marshal(f.Key)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteString("}\n")
+	_, err := io.WriteString(w, buffer.String())
+	return err
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+func writeTSVLine(w io.Writer, values []string) error {
+	for i, s := range values {
+		values[i] = tsvEscaper.Replace(s)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(values, "\t"))
+	return err
+}
+
+// writeRecords prints records as JSON Lines or TSV.
+// TSV has the header line made from the keys of the first record.
+func writeRecords(w io.Writer, format int, records []Record) error {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	for i, r := range records {
+		if format == formatJSON {
+			if err := writeJSONLine(bw, r); err != nil {
+				return err
+			}
+			continue
+		}
+		if i == 0 {
+			keys := make([]string, len(r))
+			for j, f := range r {
+				keys[j] = f.Key
+			}
+			if err := writeTSVLine(bw, keys); err != nil {
+				return err
+			}
+		}
+		values := make([]string, len(r))
+		for j, f := range r {
+			values[j] = fmt.Sprint(f.Value)
+		}
+		if err := writeTSVLine(bw, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var recordCommand ignoreCaseSorted.Dictionary[func(context.Context, Param) ([]Record, error)]
+
+// Records returns the records which the built-in command cmd.Arg(0)
+// prints with `--json`.
+func Records(ctx context.Context, cmd Param) ([]Record, error) {
+	source, ok := recordCommand.Load(cmd.Arg(0))
+	if !ok {
+		return nil, fmt.Errorf("%s: no structured output", cmd.Arg(0))
+	}
+	_, args := cutFormatOption(cmd.Args())
+	cmd.SetArgs(args)
+	return source(ctx, cmd)
+}
+
+func init() {
+	data := map[string]func(context.Context, Param) ([]Record, error){
+		"alias":    aliasRecords,
+		"diskfree": dfRecords,
+		"diskused": duRecords,
+		"dirs":     dirsRecords,
+		"env":      envRecords,
+		"history":  historyRecords,
+		"j":        jRecords,
+		"ls":       lsRecords,
+		"ps":       psRecords,
+		"trash":    trashRecords,
+		"which":    whichRecords,
+	}
+	for key, val := range data {
+		recordCommand.Store(key, val)
+	}
+}
@@ -0,0 +1,53 @@
+package commands_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+)
+
+func testRecords(t *testing.T, args ...string) []commands.Record {
+	t.Helper()
+	records, err := commands.Records(context.Background(), testCommand(t, args...))
+	if err != nil {
+		t.Fatalf("%v: %s", args, err.Error())
+	}
+	return records
+}
+
+func TestRecords(t *testing.T) {
+	records := testRecords(t, "which", "--json", "which")
+	if len(records) != 1 {
+		t.Fatalf("which --json which: %d records", len(records))
+	}
+	m := records[0].Map()
+	if m["name"] != "which" || m["type"] != "builtin" {
+		t.Fatalf("which --json which: %#v", m)
+	}
+	if records[0][0].Key != "name" {
+		t.Fatalf("which --json which: the first key is %s", records[0][0].Key)
+	}
+
+	records = testRecords(t, "dirs")
+	if len(records) < 1 || records[0].Map()["index"] != 0 {
+		t.Fatalf("dirs: %#v", records)
+	}
+}
+
+func TestRecordsNotSupported(t *testing.T) {
+	if _, err := commands.Records(context.Background(), testCommand(t, "echo", "--json")); err == nil {
+		t.Fatal("echo --json: no error")
+	}
+}
+
+func TestCutFormatOption(t *testing.T) {
+	for _, args := range [][]string{
+		{"env", "FOO=1", "nyagos-no-such-command", "--json"},
+		{"which", "which", "--json"},
+	} {
+		if rc, _ := testRun(t, nil, args...); rc == 0 {
+			t.Fatalf("%v: --json after the arguments was taken", args)
+		}
+	}
+}
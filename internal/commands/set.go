@@ -9,6 +9,7 @@ import (
 
 	"github.com/nyaosorg/go-readline-ny"
 
+	"github.com/nyaosorg/nyagos/internal/clipboard"
 	"github.com/nyaosorg/nyagos/internal/completion"
 	"github.com/nyaosorg/nyagos/internal/nodos"
 	"github.com/nyaosorg/nyagos/internal/shell"
@@ -19,6 +20,13 @@ import (
 // ReadStdinAsFile is the flat to read commands from stdin as a file stream
 var ReadStdinAsFile = false
 
+// EnableSuggestion is the flag to show the suggestion from history while typing
+var EnableSuggestion = false
+
+// QuotePaste is the flag to enclose the pasted text with quotations
+// when it has special characters
+var QuotePaste = false
+
 type optionT struct {
 	V       *bool
 	Setter  func(value bool)
@@ -85,6 +93,16 @@ var BoolOptions = ignoreCaseSorted.MapToDictionary(map[string]*optionT{
 		Usage:   "Read commands from stdin as a file stream. Disable to edit line",
 		NoUsage: "Read commands from stdin as Windows Console(tty). Enable to edit line",
 	},
+	"suggestion": {
+		V:       &EnableSuggestion,
+		Usage:   "Show the suggestion from history after the cursor",
+		NoUsage: "Do not show the suggestion from history",
+	},
+	"paste_quote": {
+		V:       &QuotePaste,
+		Usage:   "Quote the pasted text which has special characters",
+		NoUsage: "Do not quote the pasted text",
+	},
 	"output_surrogate_pair": {
 		Setter:  readline.EnableSurrogatePair,
 		Getter:  readline.IsSurrogatePairEnabled,
@@ -93,6 +111,40 @@ var BoolOptions = ignoreCaseSorted.MapToDictionary(map[string]*optionT{
 	},
 })
 
+// EditMode is the key-bindings style of the line editor: "emacs" or "vi"
+var EditMode = "emacs"
+
+type stringOptionT struct {
+	V      *string
+	Values []string
+	Usage  string
+}
+
+// Set changes the value when it is one of Values.
+func (o *stringOptionT) Set(value string) error {
+	for _, v := range o.Values {
+		if strings.EqualFold(v, value) {
+			*o.V = v
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: invalid value (%s)", value, strings.Join(o.Values, ","))
+}
+
+// StringOptions are the global options which have a string value.
+var StringOptions = ignoreCaseSorted.MapToDictionary(map[string]*stringOptionT{
+	"editmode": {
+		V:      &EditMode,
+		Values: []string{"emacs", "vi"},
+		Usage:  "Key-bindings of the line editor",
+	},
+	"clipboard": {
+		V:      &clipboard.Backend,
+		Values: []string{"auto", "system", "osc52"},
+		Usage:  "The way to access the clipboard (osc52: the escape sequence of the terminal)",
+	},
+})
+
 func dumpBoolOptions(out io.Writer) {
 	max := 0
 	for p := BoolOptions.Front(); p != nil; p = p.Next() {
@@ -152,12 +204,7 @@ func cmdSet(ctx context.Context, cmd Param) (int, error) {
 				args = args[1:]
 			}
 		} else if val := strings.ToLower(args[0]); val == "/a" || val == "-a" {
-			value, err := evalEquation(strings.Join(args[1:], " "))
-			if err != nil {
-				return 1, err
-			}
-			fmt.Fprintf(cmd.Out(), "%d\n", value)
-			return 0, nil
+			return printExpression(cmd, args[1:], false)
 		} else {
 			// environment variable operation
 			arg := strings.Join(args, " ")
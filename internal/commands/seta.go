@@ -1,394 +1,696 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 	"unicode"
 )
 
-const (
-	lshift = '\uE000' + iota
-	rshift
-	assignAdd
-	assignSub
-	assignMul
-	assignDiv
-	assignMod
-	assignAnd
-	assignOr
-	assignXor
-	assignLshift
-	assignRshift
-)
+// numberT is the value of the expression: an integer or a floating point.
+type numberT struct {
+	i       int64
+	f       float64
+	isFloat bool
+}
 
-var replacer = strings.NewReplacer(
-	"<<=", string(assignLshift),
-	">>=", string(assignRshift),
-	"<<", string(lshift),
-	">>", string(rshift),
-	"+=", string(assignAdd),
-	"-=", string(assignSub),
-	"*=", string(assignMul),
-	"/=", string(assignDiv),
-	"%=", string(assignMod),
-	"&=", string(assignAnd),
-	"|=", string(assignOr),
-	"^=", string(assignXor))
-
-func skipSpace(r io.RuneScanner) error {
-	for {
-		ch, _, err := r.ReadRune()
-		if err != nil {
-			return err
-		}
-		if !strings.ContainsRune(" \t\v\r\n", ch) {
-			r.UnreadRune()
-			return nil
-		}
+func intNumber(i int64) numberT { return numberT{i: i} }
+
+func floatNumber(f float64) numberT { return numberT{f: f, isFloat: true} }
+
+func boolNumber(b bool) numberT {
+	if b {
+		return intNumber(1)
 	}
+	return intNumber(0)
 }
 
-type valueT interface {
-	Get() int
+func (n numberT) Float() float64 {
+	if n.isFloat {
+		return n.f
+	}
+	return float64(n.i)
 }
 
-type rValueT int
+func (n numberT) IsTrue() bool {
+	if n.isFloat {
+		return n.f != 0
+	}
+	return n.i != 0
+}
 
-func (n rValueT) Get() int {
-	return int(n)
+func (n numberT) Int() (int64, error) {
+	if n.isFloat {
+		return 0, fmt.Errorf("%s: not an integer", n.String())
+	}
+	return n.i, nil
 }
 
-type lValueT string
+func (n numberT) String() string {
+	if n.isFloat {
+		return strconv.FormatFloat(n.f, 'g', 15, 64)
+	}
+	return strconv.FormatInt(n.i, 10)
+}
 
-func (name lValueT) Get() int {
-	n, err := strconv.Atoi(os.Getenv(string(name)))
+// parseNumber parses the decimal, the hexadecimal (0x..), the octal (0..),
+// the binary (0b..) and the floating point.
+func parseNumber(s string) (numberT, error) {
+	if strings.ContainsAny(s, ".eE") && !strings.HasPrefix(strings.ToLower(s), "0x") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return numberT{}, fmt.Errorf("%s: invalid number", s)
+		}
+		return floatNumber(f), nil
+	}
+	var i int64
+	var err error
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		i, err = strconv.ParseInt(s[2:], 16, 64)
+	case strings.HasPrefix(lower, "0b"):
+		i, err = strconv.ParseInt(s[2:], 2, 64)
+	case len(s) > 1 && s[0] == '0':
+		i, err = strconv.ParseInt(s[1:], 8, 64)
+	default:
+		i, err = strconv.ParseInt(s, 10, 64)
+	}
 	if err != nil {
-		return 0
+		return numberT{}, fmt.Errorf("%s: invalid number", s)
 	}
-	return n
+	return intNumber(i), nil
+}
+
+type calcToken struct {
+	kind  byte // 'n': number, 'i': identifier, 'o': operator
+	text  string
+	value numberT
+}
+
+// calcOperators are sorted so that the longer one matches first.
+var calcOperators = []string{
+	"<<=", ">>=",
+	"<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"+", "-", "*", "/", "%", "&", "|", "^", "~", "!",
+	"<", ">", "=", "?", ":", ",", "(", ")",
 }
 
-func (name lValueT) Set(value int) {
-	os.Setenv(string(name), fmt.Sprintf("%d", value))
+func isIdentRune(c rune, head bool) bool {
+	return c == '_' || unicode.IsLetter(c) || (!head && unicode.IsDigit(c))
 }
 
-func readValue(r io.RuneScanner) (valueT, error) {
-	if err := skipSpace(r); err != nil {
-		return rValueT(1), err
+func tokenizeCalc(s string) ([]calcToken, error) {
+	tokens := []calcToken{}
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		if unicode.IsSpace(c) {
+			i++
+			continue
+		}
+		if unicode.IsDigit(c) || (c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])) {
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i]) || runes[i] == '.' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E') &&
+					!strings.HasPrefix(strings.ToLower(string(runes[start:i])), "0x"))) {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := parseNumber(text)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, calcToken{kind: 'n', text: text, value: value})
+			continue
+		}
+		if isIdentRune(c, true) {
+			start := i
+			for i < len(runes) && isIdentRune(runes[i], false) {
+				i++
+			}
+			tokens = append(tokens, calcToken{kind: 'i', text: string(runes[start:i])})
+			continue
+		}
+		found := false
+		for _, op := range calcOperators {
+			if strings.HasPrefix(string(runes[i:]), op) {
+				tokens = append(tokens, calcToken{kind: 'o', text: op})
+				i += len(op)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%c: syntax error", c)
+		}
 	}
-	ch, _, err := r.ReadRune()
+	return tokens, nil
+}
+
+// calcContext is the state while the expression is evaluated.
+type calcContext struct {
+	readOnly bool // true: the assignments are not allowed (calc)
+}
+
+type calcNode func(ctx *calcContext) (numberT, error)
+
+func getVariable(name string) numberT {
+	value, err := parseNumber(strings.TrimSpace(os.Getenv(name)))
 	if err != nil {
-		return rValueT(1), err
-	}
-	if ch == '-' {
-		value, err := readValue(r)
-		return rValueT(-value.Get()), err
+		return intNumber(0)
 	}
-	if ch == '+' {
-		return readValue(r)
+	return value
+}
+
+// mulInt64 returns a*b and false when it overflows.
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
 	}
-	if ch == '~' {
-		value, err := readValue(r)
-		return rValueT(^value.Get()), err
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
 	}
-	if ch == '!' {
-		value, err := readValue(r)
-		if value.Get() != 0 {
-			return rValueT(0), err
+	return c, true
+}
+
+// intPow returns base**exp by squaring and false when it overflows.
+func intPow(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for {
+		var ok bool
+		if exp&1 != 0 {
+			if result, ok = mulInt64(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp == 0 {
+			return result, true
+		}
+		if base, ok = mulInt64(base, base); !ok {
+			return 0, false
 		}
-		return rValueT(1), err
 	}
-	if ch == '0' {
-		value := 0
-		ch, _, err := r.ReadRune()
-		if err != nil {
-			return rValueT(0), err
+}
+
+var calcFunctions = map[string]func(args []numberT) (numberT, error){
+	"abs": func(args []numberT) (numberT, error) {
+		if len(args) != 1 {
+			return numberT{}, errors.New("abs: needs one argument")
 		}
-		if ch == 'x' || ch == 'X' {
-			for {
-				ch, _, err := r.ReadRune()
-				if err != nil {
-					return rValueT(value), err
-				}
-				m := strings.IndexRune("0123456789ABCDEFF", unicode.ToUpper(ch))
-				if m < 0 {
-					r.UnreadRune()
-					return rValueT(value), nil
-				}
-				value = value*16 + m
+		if args[0].isFloat {
+			return floatNumber(math.Abs(args[0].f)), nil
+		}
+		if args[0].i < 0 {
+			return intNumber(-args[0].i), nil
+		}
+		return args[0], nil
+	},
+	"min": func(args []numberT) (numberT, error) {
+		if len(args) < 1 {
+			return numberT{}, errors.New("min: needs arguments")
+		}
+		result := args[0]
+		for _, v := range args[1:] {
+			if v.Float() < result.Float() {
+				result = v
 			}
-		} else {
-			for {
-				m := strings.IndexRune("01234567", ch)
-				if m < 0 {
-					r.UnreadRune()
-					return rValueT(value), nil
-				}
-				value = value*8 + m
-				ch, _, err = r.ReadRune()
-				if err != nil {
-					return rValueT(value), err
-				}
+		}
+		return result, nil
+	},
+	"max": func(args []numberT) (numberT, error) {
+		if len(args) < 1 {
+			return numberT{}, errors.New("max: needs arguments")
+		}
+		result := args[0]
+		for _, v := range args[1:] {
+			if v.Float() > result.Float() {
+				result = v
 			}
 		}
+		return result, nil
+	},
+	"pow": func(args []numberT) (numberT, error) {
+		if len(args) != 2 {
+			return numberT{}, errors.New("pow: needs two arguments")
+		}
+		if !args[0].isFloat && !args[1].isFloat && args[1].i >= 0 {
+			if result, ok := intPow(args[0].i, args[1].i); ok {
+				return intNumber(result), nil
+			}
+		}
+		return floatNumber(math.Pow(args[0].Float(), args[1].Float())), nil
+	},
+	"sqrt":  floatFunction("sqrt", math.Sqrt),
+	"floor": floatFunction("floor", math.Floor),
+	"ceil":  floatFunction("ceil", math.Ceil),
+	"round": floatFunction("round", math.Round),
+	"int": func(args []numberT) (numberT, error) {
+		if len(args) != 1 {
+			return numberT{}, errors.New("int: needs one argument")
+		}
+		if args[0].isFloat {
+			return intNumber(int64(args[0].f)), nil
+		}
+		return args[0], nil
+	},
+}
+
+func floatFunction(name string, f func(float64) float64) func([]numberT) (numberT, error) {
+	return func(args []numberT) (numberT, error) {
+		if len(args) != 1 {
+			return numberT{}, fmt.Errorf("%s: needs one argument", name)
+		}
+		return floatNumber(f(args[0].Float())), nil
+	}
+}
+
+// binaryOperators are the precedences of the binary operators.
+// The larger binds tighter.
+var binaryOperators = map[string]int{
+	",": 1,
+	"=": 2, "+=": 2, "-=": 2, "*=": 2, "/=": 2, "%=": 2,
+	"&=": 2, "|=": 2, "^=": 2, "<<=": 2, ">>=": 2,
+	"?":  3,
+	"||": 4,
+	"&&": 5,
+	"|":  6,
+	"^":  7,
+	"&":  8,
+	"==": 9, "!=": 9,
+	"<": 10, "<=": 10, ">": 10, ">=": 10,
+	"<<": 11, ">>": 11,
+	"+": 12, "-": 12,
+	"*": 13, "/": 13, "%": 13,
+}
+
+const (
+	precComma  = 1
+	precAssign = 2
+	precUnary  = 14
+)
+
+type calcParser struct {
+	tokens []calcToken
+	pos    int
+}
+
+func (p *calcParser) peek() *calcToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
 	}
-	if n := strings.IndexRune("0123456789", ch); n >= 0 {
-		value := n
-		for {
-			ch, _, err := r.ReadRune()
+	return nil
+}
+
+func (p *calcParser) isOperator(op string) bool {
+	t := p.peek()
+	return t != nil && t.kind == 'o' && t.text == op
+}
+
+func (p *calcParser) expect(op string) error {
+	if !p.isOperator(op) {
+		if t := p.peek(); t != nil {
+			return fmt.Errorf("%s: `%s` is expected", t.text, op)
+		}
+		return fmt.Errorf("`%s` is expected", op)
+	}
+	p.pos++
+	return nil
+}
+
+// parse reads the expression whose operators bind tighter than or equal to prec.
+func (p *calcParser) parse(prec int) (calcNode, error) {
+	left, name, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t == nil || t.kind != 'o' {
+			return left, nil
+		}
+		op := t.text
+		opPrec, ok := binaryOperators[op]
+		if !ok || opPrec < prec {
+			return left, nil
+		}
+		p.pos++
+		switch {
+		case op == "?":
+			then, err := p.parse(precAssign)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			els, err := p.parse(opPrec)
+			if err != nil {
+				return nil, err
+			}
+			left = ternaryNode(left, then, els)
+		case opPrec == precAssign:
+			if name == "" {
+				return nil, fmt.Errorf("%s: the left side is not a variable", op)
+			}
+			right, err := p.parse(opPrec) // right associative
 			if err != nil {
-				return rValueT(value), err
+				return nil, err
 			}
-			m := strings.IndexRune("0123456789", ch)
-			if m < 0 {
-				r.UnreadRune()
-				return rValueT(value), nil
+			left = assignNode(name, op, right)
+		default:
+			right, err := p.parse(opPrec + 1)
+			if err != nil {
+				return nil, err
 			}
-			value = value*10 + m
+			left = binaryNode(op, left, right)
 		}
+		name = ""
+	}
+}
+
+// parseUnary reads the operand with unary operators.
+// name is not empty when the operand is just a variable, which can be assigned.
+func (p *calcParser) parseUnary() (node calcNode, name string, err error) {
+	t := p.peek()
+	if t == nil {
+		return nil, "", errors.New("the expression is incomplete")
 	}
-	if unicode.IsLetter(ch) {
-		var name strings.Builder
-		for {
-			name.WriteRune(ch)
-			ch, _, err = r.ReadRune()
+	p.pos++
+	switch t.kind {
+	case 'n':
+		value := t.value
+		return func(*calcContext) (numberT, error) { return value, nil }, "", nil
+	case 'i':
+		name = t.text
+		if !p.isOperator("(") {
+			return func(*calcContext) (numberT, error) { return getVariable(name), nil }, name, nil
+		}
+		p.pos++
+		f, ok := calcFunctions[strings.ToLower(name)]
+		if !ok {
+			return nil, "", fmt.Errorf("%s: no such function", name)
+		}
+		args := []calcNode{}
+		for !p.isOperator(")") {
+			if len(args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, "", err
+				}
+			}
+			arg, err := p.parse(precComma + 1)
 			if err != nil {
-				break
+				return nil, "", err
 			}
-			if !unicode.IsLetter(ch) {
-				r.UnreadRune()
-				break
+			args = append(args, arg)
+		}
+		p.pos++
+		return func(ctx *calcContext) (numberT, error) {
+			values := make([]numberT, 0, len(args))
+			for _, arg := range args {
+				v, err := arg(ctx)
+				if err != nil {
+					return v, err
+				}
+				values = append(values, v)
 			}
+			return f(values)
+		}, "", nil
+	}
+	switch t.text {
+	case "(":
+		node, err := p.parse(precComma)
+		if err != nil {
+			return nil, "", err
 		}
-		return lValueT(name.String()), nil
+		if err := p.expect(")"); err != nil {
+			return nil, "", errors.New("() pair is not closed")
+		}
+		return node, "", nil
+	case "-", "+", "~", "!":
+		op := t.text
+		operand, err := p.parse(precUnary)
+		if err != nil {
+			return nil, "", err
+		}
+		return unaryNode(op, operand), "", nil
 	}
-	if ch == '(' {
-		value, err := readEquation(r)
-		if err == nil {
-			ch, _, err := r.ReadRune()
-			if err != nil || ch != ')' {
-				return value, errors.New("() pair is not closed")
+	return nil, "", fmt.Errorf("%s: syntax error", t.text)
+}
+
+func unaryNode(op string, operand calcNode) calcNode {
+	return func(ctx *calcContext) (numberT, error) {
+		v, err := operand(ctx)
+		if err != nil {
+			return v, err
+		}
+		switch op {
+		case "-":
+			if v.isFloat {
+				return floatNumber(-v.f), nil
 			}
-			return value, nil
+			return intNumber(-v.i), nil
+		case "~":
+			i, err := v.Int()
+			return intNumber(^i), err
+		case "!":
+			return boolNumber(!v.IsTrue()), nil
 		}
-		return value, errors.New("() pair is not closed")
+		return v, nil
 	}
-	return rValueT(1), errors.New("syntax error")
 }
 
-type operation struct {
-	Operator map[rune]func(v1, v2 valueT) valueT
-	Sub      func(io.RuneScanner) (valueT, error)
+func ternaryNode(cond, then, els calcNode) calcNode {
+	return func(ctx *calcContext) (numberT, error) {
+		c, err := cond(ctx)
+		if err != nil {
+			return c, err
+		}
+		if c.IsTrue() {
+			return then(ctx)
+		}
+		return els(ctx)
+	}
 }
 
-func (op *operation) Eval(r io.RuneScanner) (valueT, error) {
-	value, err := op.Sub(r)
-	if err != nil {
-		return value, err
-	}
-	for {
-		if err := skipSpace(r); err != nil {
-			return value, err
+func assignNode(name, op string, right calcNode) calcNode {
+	return func(ctx *calcContext) (numberT, error) {
+		if ctx.readOnly {
+			return numberT{}, fmt.Errorf("%s%s: the assignment is not allowed", name, op)
 		}
-		ch, _, err := r.ReadRune()
+		value, err := right(ctx)
 		if err != nil {
 			return value, err
 		}
-		if f := op.Operator[ch]; f != nil {
-			value2, err := op.Sub(r)
-			value = f(value, value2)
+		if op != "=" {
+			value, err = calcBinary(op[:len(op)-1], getVariable(name), value)
 			if err != nil {
 				return value, err
 			}
-		} else {
-			r.UnreadRune()
-			return value, nil
-		}
-	}
-}
-
-var opComma *operation
-
-func readEquation(r io.RuneScanner) (valueT, error) {
-	if opComma == nil {
-		opMulDiv := &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				'*': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() * v2.Get())
-				},
-				'/': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() / v2.Get())
-				},
-				'%': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() % v2.Get())
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return readValue(r)
-			},
-		}
-		opAddSub := &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				'+': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() + v2.Get())
-				},
-				'-': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() - v2.Get())
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return opMulDiv.Eval(r)
-			},
-		}
-		opShift := &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				lshift: func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() << uint(v2.Get()))
-				},
-				rshift: func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() >> uint(v2.Get()))
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return opAddSub.Eval(r)
-			},
-		}
-		opBitAnd := &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				'&': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() & v2.Get())
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return opShift.Eval(r)
-			},
-		}
-		opBitXor := &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				'^': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() ^ v2.Get())
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return opBitAnd.Eval(r)
-			},
-		}
-		opBitOr := &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				'|': func(v1, v2 valueT) valueT {
-					return rValueT(v1.Get() | v2.Get())
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return opBitXor.Eval(r)
-			},
-		}
-		opAssign := &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				'=': func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignAdd: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() + v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignSub: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() - v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignMul: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() * v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignDiv: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() / v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignMod: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() % v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignAnd: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() & v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignOr: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() | v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignXor: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() ^ v2.Get())
-						return V1
-					}
-					return v2
-				},
-				assignLshift: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() << uint(v2.Get()))
-						return V1
-					}
-					return v2
-				},
-				assignRshift: func(v1, v2 valueT) valueT {
-					if V1, ok := v1.(lValueT); ok {
-						V1.Set(v1.Get() >> uint(v2.Get()))
-						return V1
-					}
-					return v2
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return opBitOr.Eval(r)
-			},
-		}
-		opComma = &operation{
-			Operator: map[rune]func(v1, v2 valueT) valueT{
-				',': func(v1, v2 valueT) valueT {
-					return v2
-				},
-			},
-			Sub: func(r io.RuneScanner) (valueT, error) {
-				return opAssign.Eval(r)
-			},
-		}
-	}
-	return opComma.Eval(r)
-}
-
-func evalEquation(s string) (int, error) {
-	value, err := readEquation(strings.NewReader(replacer.Replace(s)))
-	if err == io.EOF {
-		err = nil
-	}
-	return value.Get(), err
+		}
+		os.Setenv(name, value.String())
+		return value, nil
+	}
+}
+
+func binaryNode(op string, left, right calcNode) calcNode {
+	return func(ctx *calcContext) (numberT, error) {
+		l, err := left(ctx)
+		if err != nil {
+			return l, err
+		}
+		// && and || do not evaluate the right side when unnecessary.
+		if op == "&&" && !l.IsTrue() || op == "||" && l.IsTrue() {
+			return boolNumber(l.IsTrue()), nil
+		}
+		r, err := right(ctx)
+		if err != nil {
+			return r, err
+		}
+		return calcBinary(op, l, r)
+	}
+}
+
+// compareNumbers returns -1, 0 or 1. The integers are compared as
+// int64 not to lose the digits which float64 can not hold.
+func compareNumbers(l, r numberT) int {
+	if !l.isFloat && !r.isFloat {
+		switch {
+		case l.i < r.i:
+			return -1
+		case l.i > r.i:
+			return 1
+		}
+		return 0
+	}
+	switch lf, rf := l.Float(), r.Float(); {
+	case lf < rf:
+		return -1
+	case lf > rf:
+		return 1
+	}
+	return 0
+}
+
+func calcBinary(op string, l, r numberT) (numberT, error) {
+	switch op {
+	case ",":
+		return r, nil
+	case "&&", "||":
+		return boolNumber(r.IsTrue()), nil
+	case "==":
+		return boolNumber(compareNumbers(l, r) == 0), nil
+	case "!=":
+		return boolNumber(compareNumbers(l, r) != 0), nil
+	case "<":
+		return boolNumber(compareNumbers(l, r) < 0), nil
+	case "<=":
+		return boolNumber(compareNumbers(l, r) <= 0), nil
+	case ">":
+		return boolNumber(compareNumbers(l, r) > 0), nil
+	case ">=":
+		return boolNumber(compareNumbers(l, r) >= 0), nil
+	}
+	if l.isFloat || r.isFloat {
+		a, b := l.Float(), r.Float()
+		switch op {
+		case "+":
+			return floatNumber(a + b), nil
+		case "-":
+			return floatNumber(a - b), nil
+		case "*":
+			return floatNumber(a * b), nil
+		case "/":
+			if b == 0 {
+				return numberT{}, errors.New("division by zero")
+			}
+			return floatNumber(a / b), nil
+		case "%":
+			if b == 0 {
+				return numberT{}, errors.New("division by zero")
+			}
+			return floatNumber(math.Mod(a, b)), nil
+		}
+		if l.isFloat {
+			_, err := l.Int()
+			return l, err
+		}
+		_, err := r.Int()
+		return r, err
+	}
+	a, b := l.i, r.i
+	switch op {
+	case "+":
+		return intNumber(a + b), nil
+	case "-":
+		return intNumber(a - b), nil
+	case "*":
+		return intNumber(a * b), nil
+	case "/":
+		if b == 0 {
+			return numberT{}, errors.New("division by zero")
+		}
+		return intNumber(a / b), nil
+	case "%":
+		if b == 0 {
+			return numberT{}, errors.New("division by zero")
+		}
+		return intNumber(a % b), nil
+	case "&":
+		return intNumber(a & b), nil
+	case "|":
+		return intNumber(a | b), nil
+	case "^":
+		return intNumber(a ^ b), nil
+	case "<<":
+		return intNumber(a << uint64(b)), nil
+	case ">>":
+		return intNumber(a >> uint64(b)), nil
+	}
+	return numberT{}, fmt.Errorf("%s: unknown operator", op)
+}
+
+// evalExpression evaluates the expression of `set /a` and `calc`.
+// When readOnly is true, the assignments are errors.
+func evalExpression(s string, readOnly bool) (numberT, error) {
+	tokens, err := tokenizeCalc(s)
+	if err != nil {
+		return numberT{}, err
+	}
+	if len(tokens) <= 0 {
+		return numberT{}, errors.New("the expression is empty")
+	}
+	p := &calcParser{tokens: tokens}
+	node, err := p.parse(precComma)
+	if err != nil {
+		return numberT{}, err
+	}
+	if t := p.peek(); t != nil {
+		return numberT{}, fmt.Errorf("%s: syntax error", t.text)
+	}
+	return node(&calcContext{readOnly: readOnly})
+}
+
+// formatNumber formats the value in the base of the option `/x` or `/b`.
+func formatNumber(value numberT, format string) (string, error) {
+	switch format {
+	case "/x":
+		i, err := value.Int()
+		if i < 0 {
+			return "-0x" + strconv.FormatUint(uint64(-i), 16), err
+		}
+		return "0x" + strconv.FormatInt(i, 16), err
+	case "/b":
+		i, err := value.Int()
+		if i < 0 {
+			return "-0b" + strconv.FormatUint(uint64(-i), 2), err
+		}
+		return "0b" + strconv.FormatInt(i, 2), err
+	}
+	return value.String(), nil
+}
+
+// cutFormat removes `/x` or `/b` at the head of args.
+func cutFormat(args []string) (string, []string) {
+	if len(args) > 0 {
+		if f := strings.ToLower(args[0]); f == "/x" || f == "/b" {
+			return f, args[1:]
+		}
+	}
+	return "", args
+}
+
+const calcUsage = `Usage: calc [/x|/b] EXPRESSION
+  /x   print the result in hexadecimal
+  /b   print the result in binary`
+
+func printExpression(cmd Param, args []string, readOnly bool) (int, error) {
+	format, args := cutFormat(args)
+	// The quotations are ignored as CMD.EXE does.
+	expr := strings.ReplaceAll(strings.Join(args, " "), `"`, "")
+	value, err := evalExpression(expr, readOnly)
+	if err != nil {
+		return 1, err
+	}
+	text, err := formatNumber(value, format)
+	if err != nil {
+		return 1, err
+	}
+	fmt.Fprintln(cmd.Out(), text)
+	return 0, nil
+}
+
+func cmdCalc(_ context.Context, cmd Param) (int, error) {
+	args := cmd.Args()[1:]
+	if len(args) <= 0 {
+		return 1, errors.New(calcUsage)
+	}
+	return printExpression(cmd, args, true)
 }
@@ -0,0 +1,58 @@
+package commands_test
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetA(t *testing.T) {
+	t.Setenv("SETA_X", "5")
+	for _, c := range []struct{ expr, expect string }{
+		{"1+2*3", "7\n"},
+		{"(1+2)*3", "9\n"},
+		{"7/2", "3\n"},
+		{"7/2.0", "3.5\n"},
+		{"0x1F + 010 + 0b11", "42\n"},
+		{"1 < 2 && 3 >= 3", "1\n"},
+		{"!(1 == 1) || 0", "0\n"},
+		{"9007199254740993 == 9007199254740992", "0\n"},
+		{"9007199254740993 > 9007199254740992", "1\n"},
+		{"2 == 2.0", "1\n"},
+		{"0 ? 10 : 1 ? 20 : 30", "20\n"},
+		{"max(3, 9.5, -1) + min(4, 2)", "11.5\n"},
+		{"pow(2, 10) + abs(-3)", "1027\n"},
+		{"pow(-2, 63)", "-9223372036854775808\n"},
+		{"pow(2, 63)", "9.22337203685478e+18\n"},
+		{"pow(3, 99999999999)", "+Inf\n"},
+		{"~0 << 4 | 1", "-15\n"},
+		{"SETA_X *= 2, SETA_X + 1", "11\n"},
+	} {
+		if result := testOutput(t, "set", "/a", c.expr); result != c.expect {
+			t.Fatalf("set /a %s: %q (expect %q)", c.expr, result, c.expect)
+		}
+	}
+	if result := testOutput(t, "set", "/a", "/x", "255"); result != "0xff\n" {
+		t.Fatalf("set /a /x 255: %q", result)
+	}
+	if result := testOutput(t, "set", "/a", "/b", "10"); result != "0b1010\n" {
+		t.Fatalf("set /a /b 10: %q", result)
+	}
+	if value := os.Getenv("SETA_X"); value != "10" {
+		t.Fatalf("SETA_X=%s (expect 10)", value)
+	}
+}
+
+func TestCalc(t *testing.T) {
+	t.Setenv("CALC_X", "1.5")
+	if result := testOutput(t, "calc", "CALC_X * 2 + 0.1"); result != "3.1\n" {
+		t.Fatalf("calc: %q", result)
+	}
+	for _, expr := range []string{"CALC_X = 2", "1/0", "1.5 & 1", "(1+2", "1 + x = 3"} {
+		if _, err := testRun(t, nil, "calc", expr); err == nil {
+			t.Fatalf("calc %s: no error", expr)
+		}
+	}
+	if value := os.Getenv("CALC_X"); value != "1.5" {
+		t.Fatalf("CALC_X=%s (expect 1.5)", value)
+	}
+}
@@ -3,6 +3,15 @@
 
 package commands
 
+import (
+	"strings"
+)
+
+// findBatch returns true for the scripts of sh except the scripts
+// of nyagos (*.ny), which are executed by nyagos itself.
 func findBatch(name string) (string, bool) {
+	if strings.HasSuffix(strings.ToLower(name), ".ny") {
+		return "", false
+	}
 	return name, true
 }
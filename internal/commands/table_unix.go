@@ -35,8 +35,10 @@ func init() {
 		"foreach":  cmdForeach,
 		"history":  cmdHistory,
 		"if":       cmdIf,
+		"j":        cmdJ,
 		"ln":       cmdLn,
 		"lnk":      cmdLnk,
+		"ls":       cmdLs,
 		"mklink":   cmdMklink,
 		"kill":     cmdKill,
 		"killall":  cmdKillAll,
@@ -53,10 +55,12 @@ func init() {
 		"rem":      cmdRem,
 		"rmdir":    cmdRmdir,
 		"select":   cmdShOpenWithDialog,
+		"calc":     cmdCalc,
 		"set":      cmdSet,
 		"source":   cmdSource,
 		"su":       cmdSu,
 		"touch":    cmdTouch,
+		"trash":    cmdTrash,
 		"type":     cmdType,
 		"which":    cmdWhich,
 	}
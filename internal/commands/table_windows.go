@@ -33,6 +33,7 @@ func init() {
 		"foreach":  cmdForeach,
 		"history":  cmdHistory,
 		"if":       cmdIf,
+		"j":        cmdJ,
 		"ln":       cmdLn,
 		"lnk":      cmdLnk,
 		"mklink":   cmdMklink,
@@ -52,10 +53,12 @@ func init() {
 		"rem":      cmdRem,
 		"rmdir":    cmdRmdir,
 		"select":   cmdShOpenWithDialog,
+		"calc":     cmdCalc,
 		"set":      cmdSet,
 		"source":   cmdSource,
 		"su":       cmdSu,
 		"touch":    cmdTouch,
+		"trash":    cmdTrash,
 		"type":     cmdType,
 		"which":    cmdWhich,
 	}
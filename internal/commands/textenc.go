@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/nyaosorg/go-windows-mbcs"
+)
+
+// ansiEncoding is the encoding of the console code page
+// (the encoding of $LANG on Unix).
+type ansiEncoding struct{}
+
+func (ansiEncoding) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: mbcs.NewDecoder(mbcs.ConsoleCP())}
+}
+
+func (ansiEncoding) NewEncoder() *encoding.Encoder {
+	return &encoding.Encoder{Transformer: mbcs.NewEncoder(mbcs.ConsoleCP())}
+}
+
+// autoLineEncoding decodes each line as UTF-8 or ANSI
+// like the filter of go-windows-mbcs.
+type autoLineEncoding struct{}
+
+func (autoLineEncoding) NewDecoder() *encoding.Decoder {
+	return &encoding.Decoder{Transformer: mbcs.NewAutoDecoder(mbcs.ConsoleCP())}
+}
+
+func (autoLineEncoding) NewEncoder() *encoding.Encoder {
+	return encoding.Nop.NewEncoder()
+}
+
+// encodingAliases are the names not known by htmlindex
+// and the ones whose meaning differs from it.
+var encodingAliases = map[string]encoding.Encoding{
+	"ansi":     ansiEncoding{},
+	"utf8":     unicode.UTF8,
+	"utf-8":    unicode.UTF8,
+	"utf8bom":  unicode.UTF8BOM,
+	"utf16":    unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16":   unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf16le":  unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16le": unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf16be":  unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"utf-16be": unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"sjis":     japanese.ShiftJIS,
+	"cp932":    japanese.ShiftJIS,
+	"ms932":    japanese.ShiftJIS,
+	"eucjp":    japanese.EUCJP,
+	"jis":      japanese.ISO2022JP,
+}
+
+// lookupEncoding returns the encoding of the name like `sjis`, `utf16le`
+// or the names of the WHATWG Encoding Standard like `euc-jp`, `windows-1252`.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	if enc, ok := encodingAliases[strings.ToLower(name)]; ok {
+		return enc, nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unknown encoding", name)
+	}
+	return enc, nil
+}
+
+// trimIncompleteRune removes the bytes of the last character
+// which may be cut at the end of the sample.
+func trimIncompleteRune(sample []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(sample); i++ {
+		if utf8.RuneStart(sample[len(sample)-i]) {
+			if !utf8.FullRune(sample[len(sample)-i:]) {
+				return sample[:len(sample)-i]
+			}
+			break
+		}
+	}
+	return sample
+}
+
+// countKana returns the count of Hiragana and Katakana (full-width)
+// decoded from sample with enc, or -1 when sample is not valid for enc.
+func countKana(sample []byte, enc encoding.Encoding) int {
+	decoded, err := enc.NewDecoder().Bytes(sample)
+	if err != nil {
+		return -1
+	}
+	// The last character may be cut at the end of the sample.
+	text := strings.TrimSuffix(string(decoded), "\uFFFD")
+	if strings.ContainsRune(text, utf8.RuneError) {
+		return -1
+	}
+	count := 0
+	for _, c := range text {
+		if c >= 0x3041 && c <= 0x30FF {
+			count++
+		}
+	}
+	return count
+}
+
+// detectEncoding guesses the encoding from the head of the text.
+//
+//   - UTF-16LE, UTF-16BE and UTF-8 with BOM
+//   - ISO-2022-JP by its escape sequence
+//   - Shift_JIS or EUC-JP when the text is not UTF-8 and has Kana
+//
+// Otherwise, each line is decoded as UTF-8 or ANSI.
+func detectEncoding(sample []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return unicode.UTF8BOM
+	}
+	if bytes.Contains(sample, []byte("\x1B$B")) || bytes.Contains(sample, []byte("\x1B$@")) {
+		return japanese.ISO2022JP
+	}
+	if utf8.Valid(trimIncompleteRune(sample)) {
+		return autoLineEncoding{}
+	}
+	var best encoding.Encoding = autoLineEncoding{}
+	bestScore := 0
+	for _, enc := range []encoding.Encoding{japanese.ShiftJIS, japanese.EUCJP} {
+		if score := countKana(sample, enc); score > bestScore {
+			best = enc
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// scanAnyLines is the split function of bufio.Scanner for the lines
+// ending with CRLF, LF or CR.
+func scanAnyLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// Wait for the next byte to know whether it is CRLF.
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -52,54 +54,318 @@ func readTimeStamp(s string) *time.Time {
 	return &stamp
 }
 
+// touchDateLayouts are the formats of ISO-8601 for `touch -d`.
+var touchDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var touchClockPattern = regexp.MustCompile(`^(\d{1,2}):(\d\d)(?::(\d\d)(?:\.(\d{1,9}))?)?$`)
+
+// touchUnits are the units of the relative items of `touch -d`
+// as the months, the days or the duration.
+var touchUnits = map[string]struct {
+	months   int
+	days     int
+	duration time.Duration
+}{
+	"sec":    {duration: time.Second},
+	"second": {duration: time.Second},
+	"min":    {duration: time.Minute},
+	"minute": {duration: time.Minute},
+	"hour":   {duration: time.Hour},
+	"day":    {days: 1},
+	"week":   {days: 7},
+	"month":  {months: 1},
+	"year":   {months: 12},
+}
+
+func lookupTouchUnit(word string) (months, days int, duration time.Duration, ok bool) {
+	unit, ok := touchUnits[word]
+	if !ok {
+		unit, ok = touchUnits[strings.TrimSuffix(word, "s")]
+	}
+	return unit.months, unit.days, unit.duration, ok
+}
+
+// parseTouchDate parses the date of `touch -d`: ISO-8601
+// (`2024-01-02`, `2024-01-02T10:00:00+09:00`), `@UNIXTIME` or the items
+// like `now`, `today`, `yesterday`, `tomorrow`, `10:00`, `2 hours ago`,
+// `+3 days`, `last week` and `next month` relative to now.
+func parseTouchDate(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range touchDateLayouts {
+		if stamp, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return stamp, nil
+		}
+	}
+	if strings.HasPrefix(s, "@") {
+		secStr, fracStr, _ := strings.Cut(s[1:], ".")
+		sec, err := strconv.ParseInt(secStr, 10, 64)
+		if err != nil || len(fracStr) > 9 {
+			return time.Time{}, fmt.Errorf("%s: invalid time", s)
+		}
+		nsec := 0
+		if fracStr != "" {
+			if nsec, err = strconv.Atoi((fracStr + "00000000")[:9]); err != nil {
+				return time.Time{}, fmt.Errorf("%s: invalid time", s)
+			}
+		}
+		return time.Unix(sec, int64(nsec)), nil
+	}
+	base := now
+	var clock []int
+	var months, days int
+	var duration time.Duration
+	// the relative items not negated by `ago` yet
+	var pendingMonths, pendingDays int
+	var pendingDuration time.Duration
+
+	words := strings.Fields(strings.ToLower(s))
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch word {
+		case "now", "today":
+			continue
+		case "yesterday":
+			pendingDays--
+			continue
+		case "tomorrow":
+			pendingDays++
+			continue
+		case "ago":
+			months -= pendingMonths
+			days -= pendingDays
+			duration -= pendingDuration
+			pendingMonths, pendingDays, pendingDuration = 0, 0, 0
+			continue
+		}
+		if date, err := time.ParseInLocation("2006-01-02", word, time.Local); err == nil {
+			base = time.Date(date.Year(), date.Month(), date.Day(),
+				base.Hour(), base.Minute(), base.Second(), base.Nanosecond(), time.Local)
+			continue
+		}
+		if m := touchClockPattern.FindStringSubmatch(word); m != nil {
+			hour, _ := strconv.Atoi(m[1])
+			min, _ := strconv.Atoi(m[2])
+			sec := atoiOr(m[3], 0)
+			nsec := 0
+			if m[4] != "" {
+				nsec, _ = strconv.Atoi((m[4] + "00000000")[:9])
+			}
+			if !StampIsValid(2000, 1, 1, hour, min, sec) {
+				return time.Time{}, fmt.Errorf("%s: invalid time", word)
+			}
+			clock = []int{hour, min, sec, nsec}
+			continue
+		}
+		count := 1
+		if word == "last" {
+			count = -1
+		} else if word != "next" {
+			n, err := strconv.Atoi(word)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%s: invalid date", s)
+			}
+			count = n
+		}
+		if i+1 >= len(words) {
+			return time.Time{}, fmt.Errorf("%s: no unit after %s", s, word)
+		}
+		i++
+		m, d, du, ok := lookupTouchUnit(words[i])
+		if !ok {
+			return time.Time{}, fmt.Errorf("%s: unknown unit", words[i])
+		}
+		pendingMonths += m * count
+		pendingDays += d * count
+		pendingDuration += du * time.Duration(count)
+	}
+	months += pendingMonths
+	days += pendingDays
+	duration += pendingDuration
+	if clock != nil {
+		base = time.Date(base.Year(), base.Month(), base.Day(),
+			clock[0], clock[1], clock[2], clock[3], time.Local)
+	}
+	return base.AddDate(0, months, days).Add(duration), nil
+}
+
+type touchOption struct {
+	atime     time.Time
+	mtime     time.Time
+	setAtime  bool
+	setMtime  bool
+	noCreate  bool
+	recursive bool
+}
+
+// touch creates the file or changes the timestamps of it
+// (and the files under it with opt.recursive).
+func (opt *touchOption) touch(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if opt.noCreate {
+			return nil
+		}
+		fd, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			return err
+		}
+		if err := fd.Close(); err != nil {
+			return err
+		}
+	}
+	if !opt.recursive {
+		return opt.chtimes(path)
+	}
+	// Collect the files before changing the timestamps
+	// because reading the directories updates their access time.
+	paths := []string{}
+	err := filepath.Walk(path, func(path1 string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path1 == path || info.Mode()&os.ModeSymlink == 0 {
+			paths = append(paths, path1)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, path1 := range paths {
+		if err := opt.chtimes(path1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chtimes changes the timestamps of the file.
+// When only one of the access time and the modification time is set,
+// the other one is kept.
+func (opt *touchOption) chtimes(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	atime, mtime := opt.atime, opt.mtime
+	if !opt.setAtime {
+		atime, err = fileAccessTime(path, stat)
+		if err != nil {
+			return err
+		}
+	}
+	if !opt.setMtime {
+		mtime = stat.ModTime()
+	}
+	return os.Chtimes(path, atime, mtime)
+}
+
+// touchLongOptions are the long options of touch and the short ones of them.
+var touchLongOptions = map[string]string{
+	"--date":      "-d",
+	"--reference": "-r",
+	"--no-create": "-c",
+	"--recursive": "-R",
+}
+
 func cmdTouch(ctx context.Context, this Param) (int, error) {
 	errcnt := 0
 	stamp := time.Now()
-	for i := 1; i < len(this.Args()); i++ {
-		arg1 := this.Arg(i)
-		if arg1 == "-t" {
-			i++
-			if i >= len(this.Args()) {
-				fmt.Fprintf(this.Err(), "-t: Too Few Arguments.\n")
-				return 255, nil
-			}
-			stamp1 := readTimeStamp(this.Arg(i))
-			if stamp1 == nil {
-				fmt.Fprintf(this.Err(), "-t: %s: Invalid time format.\n",
-					this.Arg(i))
+	opt := &touchOption{atime: stamp, mtime: stamp}
+	onlyAtime := false
+	onlyMtime := false
+	args := this.Args()
+	files := []string{}
+	for i := 1; i < len(args); i++ {
+		arg1 := args[i]
+		if arg1 == "--" {
+			files = append(files, args[i+1:]...)
+			break
+		}
+		if len(arg1) < 2 || arg1[0] != '-' {
+			files = append(files, arg1)
+			continue
+		}
+		value, hasValue := "", false
+		if strings.HasPrefix(arg1, "--") {
+			name, value1, found := strings.Cut(arg1, "=")
+			short, ok := touchLongOptions[name]
+			if !ok {
+				fmt.Fprintf(this.Err(), "%s: built-in touch: Not implemented.\n", arg1)
 				return 255, nil
 			}
-			stamp = *stamp1
-		} else if arg1 == "-r" {
-			i++
-			if i >= len(this.Args()) {
-				fmt.Fprintf(this.Err(), "-r: Too Few Arguments.\n")
-				return 255, nil
+			arg1, value, hasValue = short, value1, found
+		}
+		switch arg1 {
+		case "-t", "-d", "-r":
+			if !hasValue {
+				i++
+				if i >= len(args) {
+					fmt.Fprintf(this.Err(), "%s: Too Few Arguments.\n", arg1)
+					return 255, nil
+				}
+				value = args[i]
 			}
-			stat, statErr := os.Stat(this.Arg(i))
-			if statErr != nil {
-				fmt.Fprintf(this.Err(), "-r: %s: %s\n", this.Arg(i), statErr)
-				return 255, nil
+			switch arg1 {
+			case "-t":
+				stamp1 := readTimeStamp(value)
+				if stamp1 == nil {
+					fmt.Fprintf(this.Err(), "-t: %s: Invalid time format.\n", value)
+					return 255, nil
+				}
+				opt.atime, opt.mtime = *stamp1, *stamp1
+			case "-d":
+				stamp1, err := parseTouchDate(value, stamp)
+				if err != nil {
+					fmt.Fprintf(this.Err(), "-d: %s\n", err.Error())
+					return 255, nil
+				}
+				opt.atime, opt.mtime = stamp1, stamp1
+			default:
+				stat, err := os.Stat(value)
+				if err == nil {
+					opt.atime, err = fileAccessTime(value, stat)
+				}
+				if err != nil {
+					fmt.Fprintf(this.Err(), "-r: %s: %s\n", value, err)
+					return 255, nil
+				}
+				opt.mtime = stat.ModTime()
 			}
-			stamp = stat.ModTime()
-		} else if arg1[0] == '-' {
-			fmt.Fprintf(this.Err(),
-				"%s: built-in touch: Not implemented.\n",
-				arg1)
-		} else {
-			fd, err := os.OpenFile(arg1, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
-			if err == nil {
-				if err = fd.Close(); err != nil {
-					fmt.Fprintln(this.Err(), err.Error())
-					errcnt++
-					continue
+		default:
+			for _, c := range arg1[1:] {
+				switch c {
+				case 'a':
+					onlyAtime = true
+				case 'm':
+					onlyMtime = true
+				case 'c':
+					opt.noCreate = true
+				case 'R':
+					opt.recursive = true
+				default:
+					fmt.Fprintf(this.Err(), "%s: built-in touch: Not implemented.\n", arg1)
+					return 255, nil
 				}
-				os.Chtimes(arg1, stamp, stamp)
-			} else {
-				fmt.Fprintln(this.Err(), err.Error())
-				errcnt++
 			}
 		}
 	}
+	// Without -a and -m, both are changed.
+	opt.setAtime = onlyAtime || !onlyMtime
+	opt.setMtime = onlyMtime || !onlyAtime
+
+	for _, file1 := range files {
+		if err := opt.touch(file1); err != nil {
+			fmt.Fprintln(this.Err(), err.Error())
+			errcnt++
+		}
+	}
 	return errcnt, nil
 }
@@ -0,0 +1,70 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testModTime(t *testing.T, path string) time.Time {
+	t.Helper()
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return stat.ModTime()
+}
+
+func TestTouchDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+
+	testExec(t, "touch", "-d", "2024-01-02T03:04:05", path)
+	if m := testModTime(t, path); !m.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)) {
+		t.Fatalf("-d 2024-01-02T03:04:05: %v", m)
+	}
+	testExec(t, "touch", "--date=2024-03-01 12:00 +1 month -2 days", path)
+	if m := testModTime(t, path); !m.Equal(time.Date(2024, 3, 30, 12, 0, 0, 0, time.Local)) {
+		t.Fatalf("-d 2024-03-01 12:00 +1 month -2 days: %v", m)
+	}
+	testExec(t, "touch", "-d", "@1700000000.5", path)
+	if m := testModTime(t, path); !m.Equal(time.Unix(1700000000, 500000000)) {
+		t.Fatalf("-d @1700000000.5: %v", m)
+	}
+	testExec(t, "touch", "-d", "2 hours ago", path)
+	if d := time.Since(testModTime(t, path)) - 2*time.Hour; d < 0 || d > time.Minute {
+		t.Fatalf("-d 2 hours ago: %v", d)
+	}
+	testExec(t, "touch", "-d", "yesterday 10:00", path)
+	y := time.Now().AddDate(0, 0, -1)
+	if m := testModTime(t, path); !m.Equal(time.Date(y.Year(), y.Month(), y.Day(), 10, 0, 0, 0, time.Local)) {
+		t.Fatalf("-d yesterday 10:00: %v", m)
+	}
+	testExec(t, "touch", "-a", "-d", "2000-01-01", path)
+	if m := testModTime(t, path); !m.Equal(time.Date(y.Year(), y.Month(), y.Day(), 10, 0, 0, 0, time.Local)) {
+		t.Fatalf("-a changed the modification time: %v", m)
+	}
+}
+
+func TestTouchNoCreateAndRecursive(t *testing.T) {
+	dir := t.TempDir()
+	none := filepath.Join(dir, "none")
+	testExec(t, "touch", "-c", none)
+	if _, err := os.Stat(none); err == nil {
+		t.Fatal("-c created the file")
+	}
+	file := filepath.Join(dir, "sub", "f.txt")
+	if err := os.Mkdir(filepath.Dir(file), 0777); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.WriteFile(file, nil, 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	testExec(t, "touch", "-R", "-m", "-t", "202105050505", dir)
+	expect := time.Date(2021, 5, 5, 5, 5, 0, 0, time.Local)
+	for _, path := range []string{dir, filepath.Dir(file), file} {
+		if m := testModTime(t, path); !m.Equal(expect) {
+			t.Fatalf("%s: %v", path, m)
+		}
+	}
+}
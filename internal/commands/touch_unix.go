@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileAccessTime returns the last access time of the file.
+func fileAccessTime(path string, _ os.FileInfo) (time.Time, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return time.Time{}, &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+	sec, nsec := stat.Atim.Unix()
+	return time.Unix(sec, nsec), nil
+}
@@ -0,0 +1,15 @@
+package commands
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileAccessTime returns the last access time of the file.
+func fileAccessTime(_ string, stat os.FileInfo) (time.Time, error) {
+	if attr, ok := stat.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, attr.LastAccessTime.Nanoseconds()), nil
+	}
+	return stat.ModTime(), nil
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nyaosorg/nyagos/internal/nodos"
+)
+
+const trashUsage = `Usage: trash [--] FILE(S)...
+       trash list
+       trash restore {NUMBER|PATH}...
+       trash empty [/q]`
+
+const trashDateLayout = "2006-01-02 15:04:05"
+
+// listTrash returns the entries in the trash can, the oldest first.
+// The number of `trash list` is the index + 1.
+func listTrash() ([]*nodos.TrashEntry, error) {
+	entries, err := nodos.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+func samePath(a, b string) bool {
+	a = filepath.Clean(a)
+	b = filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// findTrash returns the entry by the number of `trash list`
+// or the newest one deleted from the path.
+func findTrash(entries []*nodos.TrashEntry, key string) (*nodos.TrashEntry, error) {
+	if n, err := strconv.Atoi(key); err == nil {
+		if n < 1 || n > len(entries) {
+			return nil, fmt.Errorf("%d: no such number in the trash", n)
+		}
+		return entries[n-1], nil
+	}
+	path, err := filepath.Abs(key)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if samePath(entries[i].Path, path) {
+			return entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s: not found in the trash", key)
+}
+
+func trashFiles(cmd Param, files []string) int {
+	errorcount := 0
+	for _, path := range files {
+		if err := nodos.MoveToTrash(path); err != nil {
+			fmt.Fprintf(cmd.Err(), "%s: %s\n", path, err)
+			errorcount++
+		}
+	}
+	return errorcount
+}
+
+func cmdTrash(ctx context.Context, cmd Param) (int, error) {
+	args := cmd.Args()[1:]
+	if len(args) <= 0 {
+		fmt.Fprintln(cmd.Err(), trashUsage)
+		return 0, nil
+	}
+	switch args[0] {
+	case "--":
+		return trashFiles(cmd, args[1:]), nil
+	case "list":
+		entries, err := listTrash()
+		if err != nil {
+			return 1, err
+		}
+		for i, e := range entries {
+			fmt.Fprintf(cmd.Out(), "%4d %s %s\n", i+1, e.DeletedAt.Format(trashDateLayout), e.Path)
+		}
+		return 0, nil
+	case "restore":
+		if len(args) < 2 {
+			return 1, errors.New(trashUsage)
+		}
+		entries, err := listTrash()
+		if err != nil {
+			return 1, err
+		}
+		errorcount := 0
+		for _, key := range args[1:] {
+			e, err := findTrash(entries, key)
+			if err == nil {
+				err = nodos.RestoreTrash(e)
+			}
+			if err != nil {
+				fmt.Fprintln(cmd.Err(), err.Error())
+				errorcount++
+				continue
+			}
+			fmt.Fprintf(cmd.Out(), "%s -> restored.\n", e.Path)
+		}
+		return errorcount, nil
+	case "empty":
+		if len(args) < 2 || args[1] != "/q" {
+			entries, err := listTrash()
+			if err != nil {
+				return 1, err
+			}
+			fmt.Fprintf(cmd.Err(), "Empty the trash (%d items). Are you sure? [Yes/No] ", len(entries))
+			ch, err := getkey()
+			if err != nil {
+				return 1, err
+			}
+			fmt.Fprintf(cmd.Err(), "%c ", ch)
+			if ch != 'y' && ch != 'Y' {
+				fmt.Fprintln(cmd.Err(), "-> canceled")
+				return 0, nil
+			}
+			fmt.Fprintln(cmd.Err())
+		}
+		if err := nodos.EmptyTrash(); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	default:
+		return trashFiles(cmd, args), nil
+	}
+}
+
+func trashRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	if len(cmd.Args()) != 2 || cmd.Arg(1) != "list" {
+		return nil, errors.New("trash: --json and --tsv are for `trash list`")
+	}
+	entries, err := listTrash()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(entries))
+	for i, e := range entries {
+		records = append(records, Record{
+			{"number", i + 1},
+			{"path", e.Path},
+			{"deleted", e.DeletedAt.Format(time.RFC3339)},
+			{"name", e.Name},
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+// +build !windows
+
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nyaosorg/nyagos/internal/nodos"
+)
+
+func TestTrashRestore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	// Use only the home trash above not to list and empty the real trash cans.
+	orgMountPoints := nodos.TrashMountPoints
+	nodos.TrashMountPoints = func() []string { return nil }
+	defer func() { nodos.TrashMountPoints = orgMountPoints }()
+	file := filepath.Join(dir, "a b.txt")
+	for _, text := range []string{"old", "new"} {
+		if err := os.WriteFile(file, []byte(text), 0666); err != nil {
+			t.Fatal(err.Error())
+		}
+		testExec(t, "trash", file)
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("%s: not moved to the trash", file)
+		}
+	}
+	records := testRecords(t, "trash", "list", "--json")
+	if len(records) != 2 || records[0].Map()["path"] != file {
+		t.Fatalf("trash list: %v", records)
+	}
+	info, err := os.ReadFile(filepath.Join(dir, "data", "Trash", "info", "a b.txt.trashinfo"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(string(info), "\nPath="+filepath.Join(dir, "a%20b.txt")+"\n") {
+		t.Fatalf("trashinfo: %s", info)
+	}
+
+	testExec(t, "trash", "restore", file)
+	if data, err := os.ReadFile(file); err != nil || string(data) != "new" {
+		t.Fatalf("trash restore: %q %v", data, err)
+	}
+	if records := testRecords(t, "trash", "list"); len(records) != 1 {
+		t.Fatalf("trash list after restore: %v", records)
+	}
+	testExec(t, "trash", "empty", "/q")
+	if records := testRecords(t, "trash", "list"); len(records) != 0 {
+		t.Fatalf("trash list after empty: %v", records)
+	}
+}
@@ -1,18 +1,47 @@
 package commands
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 
-	"github.com/nyaosorg/go-windows-mbcs"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+
 	"github.com/nyaosorg/nyagos/internal/nodos"
 )
 
-func cat(ctx context.Context, r io.Reader, w io.Writer) error {
-	scanner := mbcs.NewFilter(r, mbcs.ConsoleCP())
+const typeUsage = `Usage: type [-n] [-e ENCODING] [-o ENCODING] [--crlf] [FILE...]
+  -n           show line numbers
+  -e ENCODING  the encoding of the input (default: auto)
+  -o ENCODING  the encoding of the output (default: utf8)
+  --crlf       end lines with CRLF instead of LF
+ENCODING: auto, ansi, utf8, utf8bom, utf16, utf16le, utf16be, sjis, eucjp, jis
+          and the names like euc-kr, gbk, big5, windows-1252 ...`
+
+type typeOption struct {
+	decoding encoding.Encoding // nil: detect for each file
+	number   bool
+	newline  string
+	line     int
+}
+
+// cat prints the lines of r converted to UTF-8.
+// The line endings (CRLF, LF and CR) are replaced with opt.newline.
+func (opt *typeOption) cat(ctx context.Context, r io.Reader, w io.Writer) error {
+	br := bufio.NewReaderSize(r, 16*1024)
+	dec := opt.decoding
+	if dec == nil {
+		br.Peek(1)
+		sample, _ := br.Peek(br.Buffered())
+		dec = detectEncoding(sample)
+	}
+	scanner := bufio.NewScanner(transform.NewReader(br, dec.NewDecoder()))
+	scanner.Split(scanAnyLines)
 	for scanner.Scan() {
 		if done := ctx.Done(); done != nil {
 			select {
@@ -24,16 +53,63 @@ func cat(ctx context.Context, r io.Reader, w io.Writer) error {
 		}
 		text := scanner.Text()
 		text = strings.Replace(text, "\xEF\xBB\xBF", "", 1)
-		fmt.Fprintln(w, text)
-	}
-	if err := scanner.Err(); err != io.EOF {
-		return err
+		if opt.number {
+			opt.line++
+			fmt.Fprintf(w, "%6d\t", opt.line)
+		}
+		io.WriteString(w, text)
+		io.WriteString(w, opt.newline)
 	}
-	return nil
+	return scanner.Err()
 }
 
 func cmdType(ctx context.Context, cmd Param) (int, error) {
-	if len(cmd.Args()) <= 1 {
+	opt := &typeOption{newline: "\n"}
+	var output encoding.Encoding
+	args := cmd.Args()[1:]
+options:
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		switch args[0] {
+		case "--":
+			args = args[1:]
+			break options
+		case "-n":
+			opt.number = true
+		case "--crlf":
+			opt.newline = "\r\n"
+		case "-e", "-o":
+			if len(args) < 2 {
+				return 1, errors.New(typeUsage)
+			}
+			var enc encoding.Encoding
+			if args[0] == "-o" || !strings.EqualFold(args[1], "auto") {
+				var err error
+				enc, err = lookupEncoding(args[1])
+				if err != nil {
+					return 1, err
+				}
+			}
+			if args[0] == "-e" {
+				opt.decoding = enc
+			} else {
+				output = enc
+			}
+			args = args[1:]
+		case "-h", "-?", "--help":
+			fmt.Fprintln(cmd.Out(), typeUsage)
+			return 0, nil
+		default:
+			return 1, fmt.Errorf("%s: unknown option\n%s", args[0], typeUsage)
+		}
+		args = args[1:]
+	}
+	out := cmd.Out()
+	if output != nil {
+		w := transform.NewWriter(out, encoding.ReplaceUnsupported(output.NewEncoder()))
+		defer w.Close()
+		out = w
+	}
+	if len(args) <= 0 {
 		if isTerminalIn(cmd.In()) {
 			c, err := nodos.EnableProcessInput()
 			if err != nil {
@@ -41,11 +117,11 @@ func cmdType(ctx context.Context, cmd Param) (int, error) {
 			}
 			defer c()
 		}
-		if err := cat(ctx, cmd.In(), cmd.Out()); err != nil {
+		if err := opt.cat(ctx, cmd.In(), out); err != nil {
 			return 1, err
 		}
 	} else {
-		for _, arg1 := range cmd.Args()[1:] {
+		for _, arg1 := range args {
 			r, err := os.Open(arg1)
 			if err != nil {
 				return 1, err
@@ -59,7 +135,7 @@ func cmdType(ctx context.Context, cmd Param) (int, error) {
 				r.Close()
 				return 3, fmt.Errorf("%s: Permission denied", arg1)
 			}
-			err = cat(ctx, r, cmd.Out())
+			err = opt.cat(ctx, r, out)
 			r.Close()
 			if err != nil {
 				return 0, err
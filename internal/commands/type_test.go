@@ -0,0 +1,41 @@
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeDetectEncoding(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sjis.txt":    "\x82\xA0\x82\xA2\r\nx\ry",
+		"eucjp.txt":   "\xA4\xA2\xA4\xA4\r\nx\ry",
+		"utf16le.txt": "\xFF\xFEB0D0\r\x00\n\x00x\x00\r\x00y\x00",
+		"utf16be.txt": "\xFE\xFF0B0D\x00\r\x00\n\x00x\x00\r\x00y",
+		"utf8bom.txt": "\xEF\xBB\xBFあい\r\nx\ry",
+		"jis.txt":     "\x1B$B$\"$$\x1B(B\r\nx\ry",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatal(err.Error())
+		}
+		if result := testOutput(t, "type", path); result != "あい\nx\ny\n" {
+			t.Fatalf("%s: %q", name, result)
+		}
+	}
+}
+
+func TestTypeConvert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("\x82\xA0\nb\n"), 0666); err != nil {
+		t.Fatal(err.Error())
+	}
+	if result := testOutput(t, "type", "-n", "-e", "sjis", "-o", "eucjp", "--crlf", path); result != "     1\t\xA4\xA2\r\n     2\tb\r\n" {
+		t.Fatalf("%q", result)
+	}
+	if result := testOutput(t, "type", "-e", "utf8", path); result != "\uFFFD\uFFFD\nb\n" {
+		t.Fatalf("%q", result)
+	}
+}
@@ -3,11 +3,9 @@ package commands
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/nyaosorg/nyagos/internal/alias"
-	"github.com/nyaosorg/nyagos/internal/nodos"
 	"github.com/nyaosorg/nyagos/internal/shell"
 )
 
@@ -15,57 +13,97 @@ const (
 	errnoWhichNotFound = 1
 )
 
-func envToList(first1 string, envs ...string) []string {
-	result := make([]string, 1, 20)
-	result[0] = first1
-	for _, env := range envs {
-		list1 := filepath.SplitList(os.Getenv(env))
-		result = append(result, list1...)
-	}
-	return result
-}
-
-func cmdWhich(ctx context.Context, cmd Param) (int, error) {
+// which calls found for each command name in args with the kind and the value
+// in the order that the shell resolves the name:
+//
+//   - "filter": the command line rewritten by nyagos.argsfilter
+//     (the suffix handlers of suffix.lua). The rest is resolved for the new name.
+//   - "alias": the alias and its body
+//   - "function": the alias defined by the Lua function
+//   - "builtin": the built-in command
+//   - "file": the path of the executable
+//
+// Without `-a`, only the first of the aliases, the built-in command and the
+// executables is reported since it is what the shell executes.
+func which(ctx context.Context, cmd Param, args []string, found func(name, kind, value string)) error {
 	all := false
-	var pathList []string
-	var extList []string
-	for _, name := range cmd.Args()[1:] {
+	var err error
+	for _, name := range args {
 		if name == "-a" {
 			all = true
-			pathList = envToList(".", "PATH", "NYAGOSPATH")
-			extList = envToList("", "PATHEXT")
 			continue
 		}
+		if name1, cmdline, ok := whichFilter(ctx, cmd, name); ok {
+			found(name, "filter", cmdline)
+			name = name1
+		}
+		matched := false
 		if a, ok := alias.Table.Load(name); ok {
-			fmt.Fprintf(cmd.Out(), "%s: aliased to %s\n", name, a.String())
-			if !all {
-				continue
+			if f, ok := a.(*alias.Func); ok {
+				found(name, "alias", f.String())
+			} else {
+				found(name, "function", "")
 			}
+			matched = true
 		}
-		if _, ok := buildInCommand.Load(name); ok {
-			fmt.Fprintf(cmd.Out(), "%s: built-in command\n", name)
-			if !all {
-				continue
+		if !matched || all {
+			if _, _, ok := lookupBuiltin(name); ok {
+				found(name, "builtin", "")
+				matched = true
 			}
 		}
-		if all {
-			for _, dir1 := range pathList {
-				for _, ext1 := range extList {
-					fullpath1 := filepath.Join(dir1, name)
-					fullpath1 = fullpath1 + ext1
-					if _, err1 := os.Stat(fullpath1); err1 == nil {
-						fmt.Fprintln(cmd.Out(), fullpath1)
-					}
-				}
+		if !matched || all {
+			for _, path := range lookCommandPath(name, all) {
+				found(name, "file", path)
+				matched = true
 			}
+		}
+		if !matched {
+			err = fmt.Errorf("which %s: not found", name)
+		}
+	}
+	return err
+}
 
-		} else {
-			path := nodos.LookPath(shell.LookCurdirOrder, name, "NYAGOSPATH")
-			if path == "" {
-				return errnoWhichNotFound, fmt.Errorf("which %s: not found", name)
-			}
-			fmt.Fprintln(cmd.Out(), filepath.Clean(path))
+// whichFilter returns the command name and the command line
+// which the argsfilter (nyagos.argsfilter) rewrites name to.
+func whichFilter(ctx context.Context, cmd Param, name string) (string, string, bool) {
+	sh, ok := cmd.(*shell.Cmd)
+	if !ok || sh.ArgsHook == nil {
+		return "", "", false
+	}
+	args, rawargs, err := sh.ArgsHook(ctx, &sh.Shell, []string{name}, []string{name})
+	if err != nil || len(args) <= 0 || args[0] == "" || (len(args) == 1 && args[0] == name) {
+		return "", "", false
+	}
+	return args[0], strings.Join(rawargs, " "), true
+}
+
+func cmdWhich(ctx context.Context, cmd Param) (int, error) {
+	err := which(ctx, cmd, cmd.Args()[1:], func(name, kind, value string) {
+		switch kind {
+		case "filter":
+			fmt.Fprintf(cmd.Out(), "%s: filtered to %s\n", name, value)
+		case "alias":
+			fmt.Fprintf(cmd.Out(), "%s: aliased to %s\n", name, value)
+		case "function":
+			fmt.Fprintf(cmd.Out(), "%s: Lua function\n", name)
+		case "builtin":
+			fmt.Fprintf(cmd.Out(), "%s: built-in command\n", name)
+		default:
+			fmt.Fprintln(cmd.Out(), value)
 		}
+	})
+	if err != nil {
+		return errnoWhichNotFound, err
 	}
 	return 0, nil
 }
+
+func whichRecords(ctx context.Context, cmd Param) ([]Record, error) {
+	records := []Record{}
+	err := which(ctx, cmd, cmd.Args()[1:], func(name, kind, value string) {
+		records = append(records, Record{{"name", name}, {"type", kind}, {"value", value}})
+	})
+	return records, err
+}
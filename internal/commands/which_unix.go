@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// lookCommandPath returns the executables for name in the order that
+// the shell looks for. On Unix, the shell searches only %PATH% like
+// other shells (not the current directory and %NYAGOSPATH%).
+// Without all, it returns only the first one which is executed.
+func lookCommandPath(name string, all bool) []string {
+	if strings.ContainsRune(name, '/') {
+		if path, err := exec.LookPath(name); err == nil {
+			return []string{filepath.Clean(path)}
+		}
+		return nil
+	}
+	result := []string{}
+	seen := map[string]struct{}{}
+	for _, dir1 := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir1 == "" {
+			dir1 = "."
+		}
+		path, err := exec.LookPath(filepath.Join(dir1, name))
+		if err != nil {
+			continue
+		}
+		path = filepath.Clean(path)
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		result = append(result, path)
+		if !all {
+			break
+		}
+	}
+	return result
+}
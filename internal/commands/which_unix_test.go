@@ -0,0 +1,38 @@
+//go:build !windows
+// +build !windows
+
+package commands_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyaosorg/nyagos/internal/alias"
+)
+
+func TestWhichOrder(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	for _, dir := range []string{dir1, dir2} {
+		path := filepath.Join(dir, "ls")
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	t.Setenv("PATH", dir1+string(os.PathListSeparator)+dir2)
+	alias.Table.Store("ls", alias.New("ls -F"))
+	defer alias.Table.Delete("ls")
+
+	if result := testOutput(t, "which", "ls"); result != "ls: aliased to ls -F\n" {
+		t.Fatalf("which ls: %q", result)
+	}
+	expect := "ls: aliased to ls -F\nls: built-in command\n" +
+		filepath.Join(dir1, "ls") + "\n" + filepath.Join(dir2, "ls") + "\n"
+	if result := testOutput(t, "which", "-a", "ls"); result != expect {
+		t.Fatalf("which -a ls: %q", result)
+	}
+	if result := testOutput(t, "which", "__ls__"); result != "__ls__: built-in command\n" {
+		t.Fatalf("which __ls__: %q", result)
+	}
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nyaosorg/nyagos/internal/nodos"
+	"github.com/nyaosorg/nyagos/internal/shell"
+)
+
+// lookCommandPath returns the executables for name in the order that
+// the shell looks for: the current directory (by `--look-curdir-first`,
+// `--look-curdir-last` or `--look-curdir-never`), %PATH% and %NYAGOSPATH%.
+// Without all, it returns only the first one which is executed.
+func lookCommandPath(name string, all bool) []string {
+	if !all || strings.ContainsAny(name, `\/:`) {
+		if path := nodos.LookPath(shell.LookCurdirOrder, name, "NYAGOSPATH"); path != "" {
+			return []string{filepath.Clean(path)}
+		}
+		return nil
+	}
+	dirs := []string{}
+	if shell.LookCurdirOrder == nodos.LookCurdirFirst {
+		dirs = append(dirs, ".")
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	if shell.LookCurdirOrder == nodos.LookCurdirLast {
+		dirs = append(dirs, ".")
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("NYAGOSPATH"))...)
+
+	result := []string{}
+	seen := map[string]struct{}{}
+	for _, dir1 := range dirs {
+		dir1 = strings.TrimSpace(dir1)
+		if dir1 == "" {
+			continue
+		}
+		path := nodos.LookPath(shell.LookCurdirOrder, filepath.Join(dir1, name))
+		if path == "" {
+			continue
+		}
+		path = filepath.Clean(path)
+		key := strings.ToUpper(path)
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			result = append(result, path)
+		}
+	}
+	return result
+}
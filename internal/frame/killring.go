@@ -0,0 +1,149 @@
+package frame
+
+import (
+	"context"
+
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+)
+
+const killRingSize = 30
+
+var killRing []string
+
+// KillRing returns the texts removed by the kill commands.
+// The latest one is the first.
+func KillRing() []string {
+	return append([]string{}, killRing...)
+}
+
+func pushKillRing(text string) {
+	if text == "" {
+		return
+	}
+	if len(killRing) <= 0 || killRing[0] != text {
+		killRing = append([]string{text}, killRing...)
+		if len(killRing) > killRingSize {
+			killRing = killRing[:killRingSize]
+		}
+	}
+}
+
+// removedText returns the text which was in `before` and is not in `after`.
+func removedText(before, after string) string {
+	b := []rune(before)
+	a := []rune(after)
+	if len(a) >= len(b) {
+		return ""
+	}
+	head := 0
+	for head < len(a) && a[head] == b[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(a)-head && a[len(a)-1-tail] == b[len(b)-1-tail] {
+		tail++
+	}
+	return string(b[head : len(b)-tail])
+}
+
+// replaceCommand registers `f` with the name of `original` and
+// rebinds the keys bound to `original` on the global keymap.
+func replaceCommand(original *readline.GoCommand, f func(context.Context, *readline.Buffer) readline.Result) {
+	cmd := readline.NewGoCommand(original.Name, f)
+	for _, code := range keys.NameToCode {
+		if bound, ok := readline.GlobalKeyMap.Lookup(code); ok && bound == readline.Command(original) {
+			readline.GlobalKeyMap.BindKey(code, cmd)
+		}
+	}
+}
+
+// killer returns the function which calls `original` and pushes
+// the removed text to the kill-ring.
+func killer(original *readline.GoCommand) func(context.Context, *readline.Buffer) readline.Result {
+	return func(ctx context.Context, B *readline.Buffer) readline.Result {
+		before := B.String()
+		rc := original.Call(ctx, B)
+		pushKillRing(removedText(before, B.String()))
+		return rc
+	}
+}
+
+// _YankState is the text inserted by the last YANK or YANK_POP.
+// It is valid while the text and the cursor are not changed after it.
+type _YankState struct {
+	buffer *readline.Buffer
+	text   string
+	cursor int
+	start  int
+	index  int // the index of the inserted text in the kill-ring or -1
+}
+
+var lastYank _YankState
+
+func (y *_YankState) valid(B *readline.Buffer) bool {
+	return y.buffer == B && y.text == B.String() && y.cursor == B.Cursor
+}
+
+// insertYank inserts text and records it for YANK_POP.
+func insertYank(B *readline.Buffer, text string, index int) {
+	start := B.Cursor
+	B.InsertAndRepaint(text)
+	lastYank = _YankState{
+		buffer: B,
+		text:   B.String(),
+		cursor: B.Cursor,
+		start:  start,
+		index:  index,
+	}
+}
+
+// CmdYankPop replaces the text just yanked with the older one in the kill-ring.
+var CmdYankPop = readline.NewGoCommand("YANK_POP", cmdYankPop)
+
+func cmdYankPop(ctx context.Context, B *readline.Buffer) readline.Result {
+	y := &lastYank
+	if len(killRing) <= 0 || !y.valid(B) {
+		B.Out.WriteString("\a")
+		return readline.CONTINUE
+	}
+	y.index = (y.index + 1) % len(killRing)
+	B.ReplaceAndRepaint(y.start, killRing[y.index])
+	y.text = B.String()
+	y.cursor = B.Cursor
+	return readline.CONTINUE
+}
+
+// cmdYank and cmdYankWithQuote replace YANK and YANK_WITH_QUOTE of
+// go-readline-ny to paste the latest text in the kill-ring.
+// The clipboard is read by PASTE_CLIPBOARD.
+func cmdYank(ctx context.Context, B *readline.Buffer) readline.Result {
+	if len(killRing) <= 0 {
+		B.Out.WriteString("\a")
+		return readline.CONTINUE
+	}
+	insertYank(B, killRing[0], 0)
+	return readline.CONTINUE
+}
+
+func cmdYankWithQuote(ctx context.Context, B *readline.Buffer) readline.Result {
+	if len(killRing) <= 0 {
+		B.Out.WriteString("\a")
+		return readline.CONTINUE
+	}
+	B.InsertAndRepaint(quotePaste(killRing[0]))
+	return readline.CONTINUE
+}
+
+func init() {
+	replaceCommand(readline.CmdYank, cmdYank)
+	replaceCommand(readline.CmdYankWithQuote, cmdYankWithQuote)
+	for _, cmd := range []*readline.GoCommand{
+		readline.CmdKillLine,
+		readline.CmdKillWholeLine,
+		readline.CmdUnixLineDiscard,
+		readline.CmdUnixWordRubout,
+	} {
+		replaceCommand(cmd, killer(cmd))
+	}
+}
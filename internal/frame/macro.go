@@ -0,0 +1,122 @@
+package frame
+
+import (
+	"context"
+
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+)
+
+// _KbdMacro records the keys typed and replays them.
+//
+// While recording, the keymap of the editor is replaced with the one
+// whose commands record the key and call the function bound originally.
+type _KbdMacro struct {
+	recording bool
+	keys      []string
+	macro     []string
+	queue     []string // the keys to replay
+	depth     int
+	saved     readline.KeyMap
+}
+
+var kbdMacro _KbdMacro
+
+func (m *_KbdMacro) lookup(code keys.Code) readline.Command {
+	if f, ok := m.saved.Lookup(code); ok {
+		return f
+	}
+	if f, ok := readline.GlobalKeyMap.Lookup(code); ok {
+		return f
+	}
+	return readline.SelfInserter(code)
+}
+
+func (m *_KbdMacro) recorder(code keys.Code) readline.Command {
+	return &readline.GoCommand{
+		Name: "RECORD_KBD_MACRO",
+		Func: func(ctx context.Context, B *readline.Buffer) readline.Result {
+			if m.depth <= 0 {
+				m.keys = append(m.keys, string(code))
+			}
+			m.depth++
+			defer func() { m.depth-- }()
+			return m.lookup(code).Call(ctx, B)
+		},
+	}
+}
+
+// getKey reads the key for the command which reads the following keys.
+// It is replayed and recorded as same as the keys bound to commands.
+func (m *_KbdMacro) getKey(B *readline.Buffer) (string, error) {
+	if len(m.queue) > 0 {
+		key := m.queue[0]
+		m.queue = m.queue[1:]
+		return key, nil
+	}
+	key, err := B.GetKey()
+	if err == nil && m.recording {
+		m.keys = append(m.keys, key)
+	}
+	return key, err
+}
+
+// CmdStartKbdMacro starts recording keys for the keyboard macro.
+var CmdStartKbdMacro = readline.NewGoCommand("START_KBD_MACRO", func(ctx context.Context, B *readline.Buffer) readline.Result {
+	m := &kbdMacro
+	if m.recording {
+		B.Out.WriteString("\a")
+		return readline.CONTINUE
+	}
+	m.recording = true
+	m.keys = nil
+	m.saved = B.Editor.KeyMap
+
+	var recorder readline.KeyMap
+	for _, code := range keys.NameToCode {
+		recorder.BindKey(code, m.recorder(code))
+	}
+	for c := ' '; c <= '~'; c++ {
+		code := keys.Code(string(c))
+		recorder.BindKey(code, m.recorder(code))
+	}
+	recorder.BindKey(pasteStart, m.recorder(pasteStart))
+	B.Editor.KeyMap = recorder
+	return readline.CONTINUE
+})
+
+// CmdEndKbdMacro ends recording keys.
+var CmdEndKbdMacro = readline.NewGoCommand("END_KBD_MACRO", func(ctx context.Context, B *readline.Buffer) readline.Result {
+	m := &kbdMacro
+	if !m.recording {
+		B.Out.WriteString("\a")
+		return readline.CONTINUE
+	}
+	m.recording = false
+	B.Editor.KeyMap = m.saved
+	if len(m.keys) > 0 {
+		// drop the key calling END_KBD_MACRO
+		m.macro = m.keys[:len(m.keys)-1]
+	}
+	m.keys = nil
+	return readline.CONTINUE
+})
+
+// CmdCallLastKbdMacro replays the keys recorded last.
+var CmdCallLastKbdMacro = readline.NewGoCommand("CALL_LAST_KBD_MACRO", func(ctx context.Context, B *readline.Buffer) readline.Result {
+	m := &kbdMacro
+	if m.recording || len(m.macro) <= 0 {
+		B.Out.WriteString("\a")
+		return readline.CONTINUE
+	}
+	m.queue = append([]string{}, m.macro...)
+	defer func() { m.queue = nil }()
+	for len(m.queue) > 0 {
+		key := m.queue[0]
+		m.queue = m.queue[1:]
+		if rc := B.LookupCommand(key).Call(ctx, B); rc != readline.CONTINUE {
+			return rc
+		}
+	}
+	return readline.CONTINUE
+})
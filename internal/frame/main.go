@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime/debug"
 	"syscall"
 
@@ -33,6 +34,7 @@ func Start(mainHandler func() error) error {
 	})
 	completion.AppendCommandLister(commands.AllNames)
 	completion.AppendCommandLister(alias.AllNames)
+	commands.CdRankPath = filepath.Join(appDataDir(), "nyagos.dirs")
 
 	if ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED) == nil {
 		defer ole.CoUninitialize()
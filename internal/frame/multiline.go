@@ -0,0 +1,383 @@
+package frame
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+	"github.com/nyaosorg/nyagos/internal/texts"
+)
+
+const continuationPrompt = "> "
+
+const (
+	actNone = iota
+	actUp
+	actDown
+	actJoinUp
+	actJoinDown
+	actExpand
+)
+
+// _MultiLine is the buffer for the command-line which has several lines.
+// Each line is edited by readline.Editor one by one, and the other lines
+// are drawn on the rows above and below.
+type _MultiLine struct {
+	lines      []string
+	index      int // the line edited now
+	row        int // the line the screen-cursor is on
+	action     int
+	revisit    bool
+	lastPrompt string
+	plain      bool
+}
+
+// isCompleted returns true when lines have no open quotations,
+// no continuation marks and no blocks not closed.
+func (m *_MultiLine) isCompleted(lines []string) bool {
+	if len(lines) <= 0 {
+		return true
+	}
+	if endsWithSep([]byte(lines[len(lines)-1]), '^') {
+		return false
+	}
+	if m.plain {
+		return true
+	}
+	if texts.OpenQuote(strings.Join(lines, "\n")) != '\000' {
+		return false
+	}
+	nest := 0
+	for _, line := range lines {
+		nest += commands.BlockNest(line)
+	}
+	return nest <= 0
+}
+
+// statements joins the lines continued by `^` or open quotations
+// and returns the list of the commands to execute.
+func (m *_MultiLine) statements() []string {
+	result := make([]string, 0, len(m.lines))
+	var buffer strings.Builder
+	for _, line := range m.lines {
+		if endsWithSep([]byte(line), '^') {
+			buffer.WriteString(line[:len(line)-1])
+			buffer.WriteString("\r\n")
+			continue
+		}
+		buffer.WriteString(line)
+		if !m.plain && texts.OpenQuote(buffer.String()) != '\000' {
+			buffer.WriteString("\n")
+			continue
+		}
+		result = append(result, buffer.String())
+		buffer.Reset()
+	}
+	if buffer.Len() > 0 {
+		result = append(result, buffer.String())
+	}
+	return result
+}
+
+// splitLines splits the text given by history or pasting into lines.
+// Old history joined by `^` has CRLF and the mark is restored.
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, "\r") {
+			lines[i] = line[:len(line)-1] + "^"
+		}
+	}
+	return lines
+}
+
+func insertLines(lines []string, at int, values ...string) []string {
+	result := make([]string, 0, len(lines)+len(values))
+	result = append(result, lines[:at]...)
+	result = append(result, values...)
+	return append(result, lines[at:]...)
+}
+
+func removeLine(lines []string, at int) []string {
+	return append(lines[:at], lines[at+1:]...)
+}
+
+// truncateByWidth cuts s to fit in width cells.
+func truncateByWidth(s string, width readline.WidthT) string {
+	var buffer strings.Builder
+	w := readline.WidthT(0)
+	for _, c := range s {
+		w1 := readline.GetStringWidth(string(c))
+		if w+w1 > width {
+			break
+		}
+		buffer.WriteRune(c)
+		w += w1
+	}
+	return buffer.String()
+}
+
+func moveRows(w io.Writer, delta int) {
+	if delta < 0 {
+		fmt.Fprintf(w, "\x1B[%dA", -delta)
+	} else if delta > 0 {
+		fmt.Fprintf(w, "\x1B[%dB", delta)
+	}
+}
+
+func (stream *CmdStreamConsole) promptAt(k int) string {
+	prompt := stream.block.lastPrompt
+	if k > 0 {
+		prompt = continuationPrompt
+	}
+	if stream.vi.enabled() {
+		// the room for the indicator of the vi-mode
+		return strings.Repeat(" ", len(viInsertIndicator)) + prompt
+	}
+	return prompt
+}
+
+// writePrompt is the PromptWriter for the multi-line buffer.
+// On the second line or later, it prints the continuation prompt.
+// When the cursor returns to the first line, it prints only
+// the last line of the original prompt.
+func (stream *CmdStreamConsole) writePrompt(w io.Writer) (int, error) {
+	m := &stream.block
+	if m.index > 0 {
+		return io.WriteString(w, continuationPrompt)
+	}
+	if m.revisit {
+		m.revisit = false
+		return io.WriteString(w, m.lastPrompt)
+	}
+	var buffer strings.Builder
+	n, err := stream.DoPrompt(&buffer)
+	prompt := buffer.String()
+	m.lastPrompt = prompt[strings.LastIndexAny(prompt, "\r\n")+1:]
+	io.WriteString(w, prompt)
+	return n, err
+}
+
+// drawLine clears the row of the screen-cursor and prints the k-th line.
+func (stream *CmdStreamConsole) drawLine(k int) {
+	out := stream.Editor.Out
+	out.WriteString("\r\x1B[2K")
+	if k >= len(stream.block.lines) {
+		return
+	}
+	width := readline.WidthT(80)
+	if stream.termWidth > 0 {
+		width = readline.WidthT(stream.termWidth)
+	}
+	prompt := stream.promptAt(k)
+	out.WriteString(prompt)
+	room := width - readline.GetStringWidth(rxEscapeSequence.ReplaceAllString(prompt, "")) - 3
+	out.WriteString(truncateByWidth(stream.block.lines[k], room))
+}
+
+// redraw draws lines from `from` to the last (or oldLen when it is larger)
+// and moves the screen-cursor to the row of the current line.
+func (stream *CmdStreamConsole) redraw(from, oldLen int, final bool) {
+	m := &stream.block
+	out := stream.Editor.Out
+	moveRows(out, from-m.row)
+	end := len(m.lines)
+	if oldLen > end {
+		end = oldLen
+	}
+	for k := from; k < end; k++ {
+		if k > from {
+			out.WriteString("\n")
+		}
+		stream.drawLine(k)
+		m.row = k
+	}
+	if final {
+		moveRows(out, len(m.lines)-1-m.row)
+		out.WriteString("\n")
+	} else {
+		moveRows(out, m.index-m.row)
+		out.WriteString("\r")
+		m.row = m.index
+	}
+	out.Flush()
+}
+
+// moveBelow moves the screen-cursor to the next row of the last line.
+func (stream *CmdStreamConsole) moveBelow() {
+	m := &stream.block
+	out := stream.Editor.Out
+	moveRows(out, len(m.lines)-1-m.row)
+	out.WriteString("\n")
+	out.Flush()
+}
+
+// readBlock reads lines until all quotations and blocks are closed.
+func (stream *CmdStreamConsole) readBlock(ctx context.Context) ([]string, string, error) {
+	m := &stream.block
+	m.lines = []string{""}
+	m.index = 0
+	m.row = 0
+	m.revisit = false
+	stream.vi.reset()
+
+	editor := stream.Editor
+	defer func() {
+		editor.Default = ""
+		stream.coloring.defaultBits &^= quotedBit
+	}()
+	for {
+		m.action = actNone
+		editor.Default = m.lines[m.index]
+		if texts.OpenQuote(strings.Join(m.lines[:m.index], "\n")) == '"' {
+			stream.coloring.defaultBits |= quotedBit
+		} else {
+			stream.coloring.defaultBits &^= quotedBit
+		}
+		line, err := editor.ReadLine(ctx)
+		m.revisit = true
+		if err != nil {
+			if err == io.EOF && len(m.lines) > 1 {
+				continue
+			}
+			stream.moveBelow()
+			return nil, "", err
+		}
+		oldLen := len(m.lines)
+		from := m.index
+		switch m.action {
+		case actUp:
+			m.lines[m.index] = line
+			m.index--
+		case actDown:
+			m.lines[m.index] = line
+			m.index++
+		case actJoinUp:
+			prev := m.lines[m.index-1]
+			editor.Cursor = readline.MojiCountInString(prev)
+			m.lines[m.index-1] = prev + line
+			m.lines = removeLine(m.lines, m.index)
+			m.index--
+			from = m.index
+		case actJoinDown:
+			editor.Cursor = readline.MojiCountInString(line)
+			m.lines[m.index] = line + m.lines[m.index+1]
+			m.lines = removeLine(m.lines, m.index+1)
+		default:
+			pieces := splitLines(line)
+			lines := append(append(append([]string{}, m.lines[:m.index]...), pieces...), m.lines[m.index+1:]...)
+			if m.action == actExpand {
+				m.lines = lines
+				m.index += len(pieces) - 1
+				editor.Cursor = readline.MojiCountInString(m.lines[m.index])
+				break
+			}
+			if m.isCompleted(lines) {
+				m.lines = lines
+				if len(pieces) > 1 {
+					stream.redraw(from, oldLen, true)
+				} else {
+					stream.moveBelow()
+				}
+				return m.statements(), strings.Join(m.lines, "\n"), nil
+			}
+			if len(pieces) > 1 {
+				m.lines = insertLines(lines, m.index+len(pieces), "")
+				m.index += len(pieces)
+				stream.vi.reset()
+			} else {
+				var left, right strings.Builder
+				for i, c := range readline.StringToMoji(line) {
+					if i < editor.Cursor {
+						c.WriteTo(&left)
+					} else {
+						c.WriteTo(&right)
+					}
+				}
+				m.lines[m.index] = left.String()
+				m.lines = insertLines(m.lines, m.index+1, right.String())
+				m.index++
+				stream.vi.reset()
+				if right.Len() <= 0 {
+					from = m.index
+				}
+			}
+			editor.Cursor = 0
+		}
+		if m.action != actUp && m.action != actDown {
+			stream.redraw(from, oldLen, false)
+		} else {
+			moveRows(editor.Out, m.index-m.row)
+			editor.Out.WriteString("\r")
+			editor.Out.Flush()
+			m.row = m.index
+		}
+	}
+}
+
+// lineFeed is the LineFeedWriter for the multi-line buffer.
+// The screen-cursor is moved by readBlock after the editing ends.
+func (stream *CmdStreamConsole) lineFeed(_ readline.Result, w io.Writer) (int, error) {
+	stream.suggester.erase(w)
+	if width, _, err := stream.Editor.Tty.Size(); err == nil {
+		stream.termWidth = width
+	}
+	return io.WriteString(w, "\r")
+}
+
+// keyFunc returns the command which does `action` when `cond` is true.
+// Otherwise, while several lines are edited, it does nothing when `stay`
+// is true, or calls the function bound to the key on the global keymap.
+func (stream *CmdStreamConsole) keyFunc(key keys.Code, action int, stay bool, cond func(*readline.Buffer) bool) readline.Command {
+	return &readline.GoCommand{
+		Name: "MULTILINE",
+		Func: func(ctx context.Context, B *readline.Buffer) readline.Result {
+			if cond(B) {
+				stream.block.action = action
+				return readline.ENTER
+			}
+			if stay && len(stream.block.lines) > 1 {
+				return readline.CONTINUE
+			}
+			f, ok := readline.GlobalKeyMap.Lookup(key)
+			if !ok {
+				return readline.CONTINUE
+			}
+			rc := f.Call(ctx, B)
+			if rc == readline.CONTINUE && strings.ContainsRune(B.String(), '\n') {
+				// the history has several lines.
+				stream.block.action = actExpand
+				return readline.ENTER
+			}
+			return rc
+		},
+	}
+}
+
+func (stream *CmdStreamConsole) setupMultiLine() {
+	m := &stream.block
+	keyMap := &stream.Editor.KeyMap
+	for _, key := range []keys.Code{keys.Up, keys.CtrlP} {
+		keyMap.BindKey(key, stream.keyFunc(key, actUp, true, func(*readline.Buffer) bool {
+			return m.index > 0
+		}))
+	}
+	for _, key := range []keys.Code{keys.Down, keys.CtrlN} {
+		keyMap.BindKey(key, stream.keyFunc(key, actDown, true, func(*readline.Buffer) bool {
+			return m.index < len(m.lines)-1
+		}))
+	}
+	for _, key := range []keys.Code{keys.Backspace, keys.CtrlH} {
+		keyMap.BindKey(key, stream.keyFunc(key, actJoinUp, false, func(B *readline.Buffer) bool {
+			return B.Cursor == 0 && m.index > 0
+		}))
+	}
+	keyMap.BindKey(keys.Delete, stream.keyFunc(keys.Delete, actJoinDown, false, func(B *readline.Buffer) bool {
+		return B.Cursor == len(B.Buffer) && m.index < len(m.lines)-1
+	}))
+}
@@ -367,6 +367,23 @@ func OptionParse(_ctx context.Context, sh *shell.Shell, e ScriptEngineForOption)
 		})
 	}
 
+	for p := commands.StringOptions.Front(); p != nil; p = p.Next() {
+		key := p.Key
+		_val := p.Value
+		optionMap.Store("--"+strings.Replace(key, "_", "-", -1), optionT{
+			F1: func(arg string) {
+				if err := _val.Set(arg); err != nil {
+					fmt.Fprintf(os.Stderr, "--%s: %s\n", key, err.Error())
+				}
+			},
+			U: fmt.Sprintf("\"%s\"\n(lua: `nyagos.option.%s=\"%s\"`)\n%s",
+				strings.Join(_val.Values, "|"),
+				key,
+				*_val.V,
+				_val.Usage),
+		})
+	}
+
 	for i := 0; i < len(args); i++ {
 		if f, ok := optionMap.Load(args[i]); ok {
 			if f.F != nil {
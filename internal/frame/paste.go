@@ -0,0 +1,168 @@
+package frame
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+
+	"github.com/nyaosorg/nyagos/internal/clipboard"
+	"github.com/nyaosorg/nyagos/internal/commands"
+)
+
+const (
+	pasteStart = "\x1B[200~"
+	pasteEnd   = "\x1B[201~"
+)
+
+// maxKeyLength is the length of the longest escape sequence read as one key.
+var maxKeyLength = len(pasteStart)
+
+// _PasteTty is the tty which enables the bracketed-paste mode of
+// the terminal while the line is edited.
+//
+// The terminal sends the pasted text just after the start mark of
+// the bracketed paste, so escape sequences are cut at maxKeyLength
+// to read the mark as one key.
+type _PasteTty struct {
+	readline.ITty
+	out   func() io.Writer
+	count int
+}
+
+func (t *_PasteTty) Open() error {
+	if err := t.ITty.Open(); err != nil {
+		return err
+	}
+	io.WriteString(t.out(), "\x1B[?2004h")
+	return nil
+}
+
+func (t *_PasteTty) Close() error {
+	io.WriteString(t.out(), "\x1B[?2004l")
+	return t.ITty.Close()
+}
+
+func (t *_PasteTty) Raw() (func() error, error) {
+	t.count = 0
+	return t.ITty.Raw()
+}
+
+// Buffered is called after each character of the escape sequence is read.
+func (t *_PasteTty) Buffered() bool {
+	t.count++
+	return t.count < maxKeyLength && t.ITty.Buffered()
+}
+
+// quotePaste encloses s with quotations when it has special characters.
+// Double quotations are used unless s has `"` or `%`.
+func quotePaste(s string) string {
+	if !strings.ContainsAny(s, " \t&|<>()^;,=!%\"'") {
+		return s
+	}
+	if !strings.ContainsAny(s, "\"%") {
+		return `"` + s + `"`
+	}
+	if !strings.ContainsRune(s, '\'') {
+		return "'" + s + "'"
+	}
+	return s
+}
+
+// confirmPaste asks what to do with the pasted text of several lines
+// on the row below and returns the key typed.
+func (stream *CmdStreamConsole) confirmPaste(B *readline.Buffer, count int) string {
+	fmt.Fprintf(B.Out, "\n\r\x1B[2K\x1B[0;33mPasted %d lines. [y]Execute [e]Edit [n]Cancel ?\x1B[0m ", count)
+	key, err := kbdMacro.getKey(B)
+	if err != nil {
+		key = ""
+	}
+	// restore the row and go back
+	stream.drawLine(stream.block.index + 1)
+	B.Out.WriteString("\x1B[A")
+	B.RepaintAfterPrompt()
+	return key
+}
+
+func (stream *CmdStreamConsole) cmdPaste(ctx context.Context, B *readline.Buffer) readline.Result {
+	var buffer strings.Builder
+	for !strings.HasSuffix(buffer.String(), pasteEnd) {
+		key, err := kbdMacro.getKey(B)
+		if err != nil {
+			break
+		}
+		buffer.WriteString(key)
+	}
+	text := strings.TrimSuffix(buffer.String(), pasteEnd)
+	return stream.insertPasted(B, text, commands.QuotePaste)
+}
+
+// insertPasted inserts the text of one line (quoted when `quote` is true)
+// or asks what to do with the text of several lines.
+func (stream *CmdStreamConsole) insertPasted(B *readline.Buffer, text string, quote bool) readline.Result {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.TrimRight(text, "\n\000")
+	if text == "" {
+		return readline.CONTINUE
+	}
+	if !strings.ContainsRune(text, '\n') {
+		if quote {
+			text = quotePaste(text)
+		}
+		B.InsertAndRepaint(text)
+		return readline.CONTINUE
+	}
+	switch strings.ToLower(stream.confirmPaste(B, strings.Count(text, "\n")+1)) {
+	case "y":
+		stream.block.action = actNone
+	case "e":
+		stream.block.action = actExpand
+	default:
+		return readline.CONTINUE
+	}
+	// The lines are split and redrawn by readBlock.
+	B.Cursor += B.InsertString(B.Cursor, text)
+	return readline.ENTER
+}
+
+// pasteConsole is the console stream which PASTE_CLIPBOARD inserts into.
+var pasteConsole *CmdStreamConsole
+
+// CmdPasteClipboard inserts the text in the clipboard as if it were pasted
+// with the terminal. A text of one line is always quoted when it has
+// special characters.
+var CmdPasteClipboard = readline.NewGoCommand("PASTE_CLIPBOARD", cmdPasteClipboard)
+
+func cmdPasteClipboard(ctx context.Context, B *readline.Buffer) readline.Result {
+	text, err := clipboard.ReadAll()
+	if err != nil || pasteConsole == nil {
+		B.Out.WriteString("\a")
+		return readline.CONTINUE
+	}
+	return pasteConsole.insertPasted(B, text, true)
+}
+
+func (stream *CmdStreamConsole) setupPaste() {
+	editor := stream.Editor
+	editor.Tty = &_PasteTty{
+		ITty: editor.Tty,
+		out:  func() io.Writer { return editor.Out },
+	}
+	editor.KeyMap.BindKey(pasteStart, &readline.GoCommand{
+		Name: "PASTE",
+		Func: stream.cmdPaste,
+	})
+	pasteConsole = stream
+}
+
+func init() {
+	for _, code := range keys.NameToCode {
+		if len(code) > maxKeyLength {
+			maxKeyLength = len(code)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package frame
+
+import (
+	"context"
+
+	"github.com/nyaosorg/go-readline-ny"
+)
+
+// _UndoNode is a text of the line in the undo tree.
+type _UndoNode struct {
+	text   string
+	cursor int
+	seq    int // the order the node was made
+	parent *_UndoNode
+	latest *_UndoNode // the child which REDO goes to
+}
+
+// _UndoTree keeps all the texts of the line being edited as a tree
+// (like undo-tree of Emacs and the undo branches of Vim).
+// Editing the text after UNDO makes a new branch and does not discard
+// the texts undone.
+//
+// The text is watched through Coloring (every repaint calls it) and
+// is recorded just before the next key is read.
+type _UndoTree struct {
+	readline.Coloring
+	nodes   []*_UndoNode // in the order made
+	current *_UndoNode
+
+	runes  []rune
+	cursor int
+}
+
+var undoTree = &_UndoTree{}
+
+func (t *_UndoTree) Init() readline.ColorSequence {
+	t.runes = t.runes[:0]
+	t.cursor = -1
+	return t.Coloring.Init()
+}
+
+func (t *_UndoTree) Next(codepoint rune) readline.ColorSequence {
+	if codepoint == readline.CursorPositionDummyRune {
+		t.cursor = len(t.runes)
+	} else {
+		t.runes = append(t.runes, codepoint)
+	}
+	return t.Coloring.Next(codepoint)
+}
+
+func (t *_UndoTree) reset() {
+	t.nodes = t.nodes[:0]
+	t.current = nil
+	t.runes = t.runes[:0]
+	t.cursor = -1
+}
+
+// record adds the text as a child of the current node when it is changed.
+func (t *_UndoTree) record(text string, cursor int) {
+	if t.current != nil && t.current.text == text {
+		t.current.cursor = cursor
+		return
+	}
+	node := &_UndoNode{
+		text:   text,
+		cursor: cursor,
+		seq:    len(t.nodes),
+		parent: t.current,
+	}
+	if t.current != nil {
+		t.current.latest = node
+	}
+	t.nodes = append(t.nodes, node)
+	t.current = node
+}
+
+// recordRepainted records the text seen on the last repaint.
+func (t *_UndoTree) recordRepainted() {
+	cursor := t.cursor
+	if cursor < 0 {
+		cursor = len(t.runes)
+	}
+	t.record(string(t.runes), cursor)
+}
+
+// moveTo replaces the line with the text of the node.
+// REDO from the ancestors of the node follows the path to it.
+func (t *_UndoTree) moveTo(B *readline.Buffer, node *_UndoNode) {
+	for n := node; n.parent != nil; n = n.parent {
+		n.parent.latest = n
+	}
+	t.current = node
+
+	B.Cursor = len(B.Buffer)
+	B.ReplaceAndRepaint(0, node.text)
+	B.Cursor = node.cursor
+	if B.Cursor > len(B.Buffer) {
+		B.Cursor = len(B.Buffer)
+	}
+	B.RepaintAfterPrompt()
+}
+
+// undoCommand returns the command which moves to the node selected by
+// `next` and rings the bell when it returns nil.
+func undoCommand(next func(t *_UndoTree) *_UndoNode) func(context.Context, *readline.Buffer) readline.Result {
+	return func(ctx context.Context, B *readline.Buffer) readline.Result {
+		t := undoTree
+		// The commands run by a keyboard macro are not recorded yet.
+		t.record(B.String(), B.Cursor)
+		if node := next(t); node != nil {
+			t.moveTo(B, node)
+		} else {
+			B.Out.WriteString("\a")
+		}
+		return readline.CONTINUE
+	}
+}
+
+// cmdUndo replaces UNDO of go-readline-ny and goes back to the parent.
+var cmdUndo = undoCommand(func(t *_UndoTree) *_UndoNode {
+	return t.current.parent
+})
+
+// CmdRedo goes to the child undone last.
+var CmdRedo = readline.NewGoCommand("REDO", undoCommand(func(t *_UndoTree) *_UndoNode {
+	return t.current.latest
+}))
+
+// CmdUndoEarlier goes to the text made just before the current one
+// in time, even if it is on another branch.
+var CmdUndoEarlier = readline.NewGoCommand("UNDO_EARLIER", undoCommand(func(t *_UndoTree) *_UndoNode {
+	if seq := t.current.seq - 1; seq >= 0 {
+		return t.nodes[seq]
+	}
+	return nil
+}))
+
+// CmdUndoLater goes to the text made just after the current one in time.
+var CmdUndoLater = readline.NewGoCommand("UNDO_LATER", undoCommand(func(t *_UndoTree) *_UndoNode {
+	if seq := t.current.seq + 1; seq < len(t.nodes) {
+		return t.nodes[seq]
+	}
+	return nil
+}))
+
+// _UndoTreeTty is the tty which lets _UndoTree record the text before
+// reading a key. The tree is cleared when a new line is started.
+type _UndoTreeTty struct {
+	readline.ITty
+	tree *_UndoTree
+}
+
+func (t *_UndoTreeTty) Open() error {
+	t.tree.reset()
+	return t.ITty.Open()
+}
+
+func (t *_UndoTreeTty) Raw() (func() error, error) {
+	t.tree.recordRepainted()
+	return t.ITty.Raw()
+}
+
+func (stream *CmdStreamConsole) setupUndoTree() {
+	editor := stream.Editor
+	undoTree.Coloring = editor.Coloring
+	editor.Coloring = undoTree
+	if editor.Tty == nil {
+		editor.Init()
+	}
+	editor.Tty = &_UndoTreeTty{
+		ITty: editor.Tty,
+		tree: undoTree,
+	}
+}
+
+func init() {
+	replaceCommand(readline.CmdUndo, cmdUndo)
+}
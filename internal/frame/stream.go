@@ -1,7 +1,6 @@
 package frame
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -23,31 +22,48 @@ type CmdStreamConsole struct {
 	History  *history.Container
 	Editor   *readline.Editor
 	HistPath string
+
+	coloring  *_Coloring
+	suggester *_Suggester
+	vi        *_ViMode
+	block     _MultiLine
+	termWidth int
+	pending   []string
 }
 
 func NewCmdStreamConsole(doPrompt func(io.Writer) (int, error)) *CmdStreamConsole {
 	history1 := &history.Container{}
+	coloring := &_Coloring{}
 	stream := &CmdStreamConsole{
-		History: history1,
+		DoPrompt: doPrompt,
+		History:  history1,
 		Editor: &readline.Editor{
 			History:        history1,
-			PromptWriter:   doPrompt,
 			Writer:         colorable.NewColorableStdout(),
-			Coloring:       &_Coloring{},
+			Coloring:       coloring,
 			HistoryCycling: true,
 		},
+		coloring: coloring,
 		HistPath: filepath.Join(appDataDir(), "nyagos.history"),
 		CmdSeeker: shell.CmdSeeker{
 			PlainHistory: []string{},
 			Pointer:      -1,
 		},
 	}
+	stream.Editor.PromptWriter = stream.writePrompt
+	stream.Editor.LineFeedWriter = stream.lineFeed
+	stream.setupViMode()
+	stream.setupSuggester()
+	stream.setupUndoTree()
+	stream.setupPaste()
+	stream.setupMultiLine()
 	history1.Load(stream.HistPath)
 	history1.Save(stream.HistPath)
 	return stream
 }
 
 func (stream *CmdStreamConsole) DisableHistory(value bool) bool {
+	stream.block.plain = value
 	return stream.History.IgnorePush(value)
 }
 
@@ -66,30 +82,6 @@ func endsWithSep(line []byte, contMark byte) bool {
 	return markCount%2 != 0
 }
 
-func (stream *CmdStreamConsole) readLineContinued(ctx context.Context) (string, error) {
-	continued := false
-	originalPrompt := os.Getenv("PROMPT")
-	buffer := make([]byte, 0, 256)
-	for {
-		line, err := stream.Editor.ReadLine(ctx)
-		buffer = append(buffer, line...)
-		if err != nil || !endsWithSep(buffer, '^') {
-			if continued {
-				os.Setenv("PROMPT", originalPrompt)
-				stream.Editor.Coloring.(*_Coloring).defaultBits &^= quotedBit
-			}
-			return string(buffer), err
-		}
-		buffer = buffer[:len(buffer)-1]
-		buffer = append(buffer, '\r', '\n')
-		continued = true
-		os.Setenv("PROMPT", "> ")
-		if bytes.Count(buffer, []byte{'"'})%2 != 0 {
-			stream.Editor.Coloring.(*_Coloring).defaultBits |= quotedBit
-		}
-	}
-}
-
 func (stream *CmdStreamConsole) ReadLine(ctx context.Context) (context.Context, string, error) {
 	if stream.Pointer >= 0 {
 		if stream.Pointer < len(stream.PlainHistory) {
@@ -98,33 +90,43 @@ func (stream *CmdStreamConsole) ReadLine(ctx context.Context) (context.Context,
 		}
 		stream.Pointer = -1
 	}
-	var line string
+	if len(stream.pending) > 0 {
+		line := stream.pending[0]
+		stream.pending = stream.pending[1:]
+		stream.PlainHistory = append(stream.PlainHistory, line)
+		return ctx, line, nil
+	}
+	var text string
+	var statements []string
 	var err error
+	stream.suggester.ctx = ctx
 	for {
 		disabler := colorable.EnableColorsStdout(nil)
 		clean, err2 := consoleicon.SetFromExe()
-		line, err = stream.readLineContinued(ctx)
+		statements, text, err = stream.readBlock(ctx)
 		if err2 == nil {
 			clean(false)
 		}
 		disabler()
 		if err != nil {
-			return ctx, line, err
+			return ctx, "", err
 		}
 		var isReplaced bool
-		line, isReplaced, err = stream.History.Replace(line)
+		text, isReplaced, err = stream.History.Replace(text)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
 			continue
 		}
 		if isReplaced {
-			fmt.Fprintln(os.Stdout, line)
+			fmt.Fprintln(os.Stdout, text)
+			stream.block.lines = splitLines(text)
+			statements = stream.block.statements()
 		}
-		if line != "" {
+		if text != "" {
 			break
 		}
 	}
-	row := history.NewHistoryLine(line)
+	row := history.NewHistoryLine(text)
 	stream.History.PushLine(row)
 	fd, err := os.OpenFile(stream.HistPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
 	if err == nil {
@@ -133,6 +135,8 @@ func (stream *CmdStreamConsole) ReadLine(ctx context.Context) (context.Context,
 	} else {
 		fmt.Fprintln(os.Stderr, err.Error())
 	}
+	line := statements[0]
+	stream.pending = statements[1:]
 	stream.PlainHistory = append(stream.PlainHistory, line)
 	return ctx, line, err
 }
@@ -0,0 +1,215 @@
+package frame
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+	"github.com/nyaosorg/nyagos/internal/history"
+)
+
+// SuggestHook is called to get the suggestion for the current line
+// before history is searched. When it returns false, history is used.
+var SuggestHook func(ctx context.Context, text string) (string, bool)
+
+// _Suggester shows the rest of the line suggested from history
+// as dimmed text after the cursor (like fish).
+//
+// It watches the text of the line through Coloring (every repaint
+// calls it) and draws the suggestion just before the next key is read.
+type _Suggester struct {
+	readline.Coloring
+	history *history.Container
+	ctx     context.Context
+
+	runes       []rune
+	cursor      int
+	fresh       bool
+	shown       bool
+	promptWidth readline.WidthT
+
+	lastText    string
+	lastSuggest string
+}
+
+func (s *_Suggester) Init() readline.ColorSequence {
+	s.runes = s.runes[:0]
+	s.cursor = -1
+	s.fresh = true
+	s.shown = false
+	return s.Coloring.Init()
+}
+
+func (s *_Suggester) Next(codepoint rune) readline.ColorSequence {
+	if codepoint == readline.CursorPositionDummyRune {
+		s.cursor = len(s.runes)
+	} else {
+		s.runes = append(s.runes, codepoint)
+	}
+	return s.Coloring.Next(codepoint)
+}
+
+// lookup returns the rest of the suggested line for text.
+func (s *_Suggester) lookup(text string) string {
+	if !commands.EnableSuggestion || strings.TrimSpace(text) == "" {
+		return ""
+	}
+	if text == s.lastText {
+		return s.lastSuggest
+	}
+	s.lastText = text
+	s.lastSuggest = ""
+	if SuggestHook != nil {
+		ctx := s.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if line, ok := SuggestHook(ctx, text); ok {
+			if len(line) > len(text) && strings.HasPrefix(line, text) && !strings.ContainsAny(line, "\r\n") {
+				s.lastSuggest = line[len(text):]
+			}
+			return s.lastSuggest
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = ""
+	}
+	s.lastSuggest = s.history.Suggest(text, wd)
+	return s.lastSuggest
+}
+
+var rxEscapeSequence = regexp.MustCompile("\x1B\\[[0-9;?]*[A-Za-z]|\x1B\\][^\x07]*\x07")
+
+// wrapPrompt returns the prompt-writer which remembers the width
+// of the last line of the prompt.
+func (s *_Suggester) wrapPrompt(f func(io.Writer) (int, error)) func(io.Writer) (int, error) {
+	return func(w io.Writer) (int, error) {
+		var buffer strings.Builder
+		n, err := f(&buffer)
+		prompt := buffer.String()
+		io.WriteString(w, prompt)
+
+		prompt = rxEscapeSequence.ReplaceAllString(prompt, "")
+		if pos := strings.LastIndexAny(prompt, "\r\n"); pos >= 0 {
+			prompt = prompt[pos+1:]
+		}
+		s.promptWidth = readline.GetStringWidth(prompt)
+		return n, err
+	}
+}
+
+// draw prints the suggestion after the cursor when the cursor is
+// at the end of the line and the line has been repainted since
+// the last call.
+func (s *_Suggester) draw(out io.Writer, termWidth int) {
+	if !s.fresh {
+		return
+	}
+	s.fresh = false
+	if s.cursor != len(s.runes) {
+		return
+	}
+	text := string(s.runes)
+	suggest := s.lookup(text)
+	if suggest == "" {
+		return
+	}
+	room := readline.WidthT(termWidth) - s.promptWidth - readline.GetStringWidth(text) - 3
+	if room <= 0 {
+		return
+	}
+	var buffer strings.Builder
+	width := readline.WidthT(0)
+	for _, c := range suggest {
+		w := readline.GetStringWidth(string(c))
+		if width+w > room {
+			break
+		}
+		buffer.WriteRune(c)
+		width += w
+	}
+	if width <= 0 {
+		return
+	}
+	fmt.Fprintf(out, "\x1B[0;90m%s\x1B[0m\x1B[%dD", buffer.String(), width)
+	s.shown = true
+}
+
+// erase clears the suggestion shown before the line is accepted.
+func (s *_Suggester) erase(w io.Writer) {
+	if s.shown {
+		s.shown = false
+		io.WriteString(w, "\x1B[0K")
+	}
+}
+
+// acceptOr returns the command that inserts the suggestion when it exists,
+// otherwise calls the function bound to the key on the global keymap.
+func (s *_Suggester) acceptOr(key keys.Code) readline.Command {
+	return &readline.GoCommand{
+		Name: "ACCEPT_SUGGESTION",
+		Func: func(ctx context.Context, B *readline.Buffer) readline.Result {
+			if B.Cursor == len(B.Buffer) {
+				if suggest := s.lookup(B.String()); suggest != "" {
+					B.InsertAndRepaint(suggest)
+					return readline.CONTINUE
+				}
+			}
+			if f, ok := readline.GlobalKeyMap.Lookup(key); ok {
+				return f.Call(ctx, B)
+			}
+			return readline.CONTINUE
+		},
+	}
+}
+
+// _SuggestTty is the tty which lets _Suggester draw before reading a key.
+type _SuggestTty struct {
+	readline.ITty
+	suggester *_Suggester
+	out       func() io.Writer
+}
+
+func (t *_SuggestTty) Raw() (func() error, error) {
+	if commands.EnableSuggestion {
+		if w, _, err := t.Size(); err == nil {
+			out := t.out()
+			t.suggester.draw(out, w)
+			if f, ok := out.(interface{ Flush() error }); ok {
+				f.Flush()
+			}
+		}
+	}
+	return t.ITty.Raw()
+}
+
+func (stream *CmdStreamConsole) setupSuggester() {
+	editor := stream.Editor
+	s := &_Suggester{
+		Coloring: editor.Coloring,
+		history:  stream.History,
+	}
+	editor.Coloring = s
+	editor.PromptWriter = s.wrapPrompt(editor.PromptWriter)
+	if editor.Tty == nil {
+		// Init sets the TTY selected by the build tag (tty8 or tty10).
+		editor.Init()
+	}
+	editor.Tty = &_SuggestTty{
+		ITty:      editor.Tty,
+		suggester: s,
+		out:       func() io.Writer { return editor.Out },
+	}
+	for _, key := range []keys.Code{keys.Right, keys.End, keys.CtrlF, keys.CtrlE} {
+		editor.KeyMap.BindKey(key, s.acceptOr(key))
+	}
+	stream.suggester = s
+}
@@ -0,0 +1,505 @@
+package frame
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/nyaosorg/go-readline-ny"
+	"github.com/nyaosorg/go-readline-ny/keys"
+
+	"github.com/nyaosorg/nyagos/internal/commands"
+)
+
+const (
+	viInsertIndicator = "(ins) "
+	viNormalIndicator = "(cmd) "
+)
+
+// _ViMode implements the vi-like key-bindings.
+//
+// The printable keys are bound to the commands which work as vi
+// commands on the normal state and insert themselves on the insert state.
+// The other keys work as the emacs-like ones on both states.
+type _ViMode struct {
+	normal      bool
+	insertStart int
+	register    string
+
+	keys       []string // keys of the command executing now
+	lastChange []string // keys of the last command which changed the line
+	lastInsert string   // text inserted by the last change
+	replaying  bool
+
+	changing bool // the insert state was started by a command
+
+	lastFind string // f,F,t or T
+	lastChar string
+	editor   *readline.Editor
+}
+
+func (v *_ViMode) enabled() bool {
+	return commands.EditMode == "vi"
+}
+
+// reset returns to the insert state for the new command-line.
+func (v *_ViMode) reset() {
+	v.normal = false
+	v.changing = false
+	v.insertStart = 0
+}
+
+// wrapPrompt returns the prompt-writer which puts the mode indicator
+// at the head of the last line of the prompt.
+func (v *_ViMode) wrapPrompt(f func(io.Writer) (int, error)) func(io.Writer) (int, error) {
+	return func(w io.Writer) (int, error) {
+		if !v.enabled() {
+			return f(w)
+		}
+		var buffer strings.Builder
+		n, err := f(&buffer)
+		prompt := buffer.String()
+		pos := strings.LastIndexAny(prompt, "\r\n") + 1
+		io.WriteString(w, prompt[:pos])
+		io.WriteString(w, v.indicator())
+		io.WriteString(w, prompt[pos:])
+		return n + len(viInsertIndicator), err
+	}
+}
+
+func (v *_ViMode) indicator() string {
+	if v.normal {
+		return viNormalIndicator
+	}
+	return viInsertIndicator
+}
+
+// showMode rewrites the mode indicator on the screen.
+func (v *_ViMode) showMode(B *readline.Buffer) {
+	if v.replaying {
+		return
+	}
+	B.Out.WriteString("\r")
+	B.Out.WriteString(v.indicator())
+	B.DrawFromHead()
+}
+
+func (v *_ViMode) startInsert(B *readline.Buffer, pos int) {
+	B.Cursor = pos
+	v.insertStart = pos
+	v.normal = false
+	v.changing = true
+	v.showMode(B)
+}
+
+// escape leaves the insert state.
+func (v *_ViMode) escape(B *readline.Buffer) {
+	if v.changing && B.Cursor >= v.insertStart && !v.replaying {
+		v.lastInsert = B.SubString(v.insertStart, B.Cursor)
+	}
+	v.changing = false
+	v.normal = true
+	if B.Cursor > 0 {
+		B.Cursor--
+	}
+	v.showMode(B)
+}
+
+func (v *_ViMode) cells(B *readline.Buffer) []string {
+	result := make([]string, len(B.Buffer))
+	for i := range B.Buffer {
+		result[i] = B.SubString(i, i+1)
+	}
+	return result
+}
+
+// charClass returns 0 for spaces, 1 for letters and 2 for symbols.
+// When bigWord is true, both of letters and symbols are 1.
+func charClass(s string, bigWord bool) int {
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			return 0
+		}
+		if bigWord || c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return 1
+		}
+		return 2
+	}
+	return 0
+}
+
+// motion returns the position where the motion `key` moves the cursor
+// and whether the character on the position is included by operators.
+func (v *_ViMode) motion(B *readline.Buffer, key string, count int, next func() string) (int, bool, bool) {
+	cells := v.cells(B)
+	n := len(cells)
+	p := B.Cursor
+	if count <= 0 {
+		count = 1
+	}
+	switch key {
+	case "h", keys.Left, keys.Backspace:
+		p -= count
+		if p < 0 {
+			p = 0
+		}
+		return p, false, true
+	case "l", " ", keys.Right:
+		p += count
+		if p > n {
+			p = n
+		}
+		return p, false, true
+	case "0", keys.Home:
+		return 0, false, true
+	case "^":
+		for p = 0; p < n-1 && charClass(cells[p], false) == 0; p++ {
+		}
+		return p, false, true
+	case "$", keys.End:
+		if n <= 0 {
+			return 0, false, true
+		}
+		return n - 1, true, true
+	case "w", "W":
+		big := key == "W"
+		for ; count > 0 && p < n; count-- {
+			if c := charClass(cells[p], big); c != 0 {
+				for p < n && charClass(cells[p], big) == c {
+					p++
+				}
+			}
+			for p < n && charClass(cells[p], big) == 0 {
+				p++
+			}
+		}
+		return p, false, true
+	case "b", "B":
+		big := key == "B"
+		for ; count > 0 && p > 0; count-- {
+			p--
+			for p > 0 && charClass(cells[p], big) == 0 {
+				p--
+			}
+			c := charClass(cells[p], big)
+			for p > 0 && charClass(cells[p-1], big) == c {
+				p--
+			}
+		}
+		return p, false, true
+	case "e", "E":
+		big := key == "E"
+		for ; count > 0 && p < n-1; count-- {
+			p++
+			for p < n-1 && charClass(cells[p], big) == 0 {
+				p++
+			}
+			c := charClass(cells[p], big)
+			for p < n-1 && charClass(cells[p+1], big) == c {
+				p++
+			}
+		}
+		return p, true, true
+	case "f", "F", "t", "T":
+		v.lastFind = key
+		v.lastChar = next()
+		return v.find(cells, p, key, v.lastChar, count)
+	case ";", ",":
+		if v.lastFind == "" {
+			return p, false, false
+		}
+		find := v.lastFind
+		if key == "," {
+			find = map[string]string{"f": "F", "F": "f", "t": "T", "T": "t"}[find]
+		}
+		return v.find(cells, p, find, v.lastChar, count)
+	}
+	return p, false, false
+}
+
+func (v *_ViMode) find(cells []string, p int, key, target string, count int) (int, bool, bool) {
+	if key == "f" || key == "t" {
+		for i := p + 1; i < len(cells); i++ {
+			if cells[i] == target {
+				if count--; count <= 0 {
+					if key == "t" {
+						return i - 1, true, true
+					}
+					return i, true, true
+				}
+			}
+		}
+		return p, false, false
+	}
+	for i := p - 1; i >= 0; i-- {
+		if cells[i] == target {
+			if count--; count <= 0 {
+				if key == "T" {
+					return i + 1, false, true
+				}
+				return i, false, true
+			}
+		}
+	}
+	return p, false, false
+}
+
+// moveTo moves the cursor on the normal state and repaints.
+func (v *_ViMode) moveTo(B *readline.Buffer, pos int) {
+	if pos >= len(B.Buffer) {
+		pos = len(B.Buffer) - 1
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	B.Cursor = pos
+	B.RepaintAfterPrompt()
+}
+
+// operate does the operator `op` (d, c or y) for the range [from,to).
+func (v *_ViMode) operate(B *readline.Buffer, op string, from, to int) {
+	if from > to {
+		from, to = to, from
+	}
+	if from < 0 {
+		from = 0
+	}
+	if to > len(B.Buffer) {
+		to = len(B.Buffer)
+	}
+	if from < to {
+		v.register = B.SubString(from, to)
+	}
+	switch op {
+	case "d":
+		B.Delete(from, to-from)
+		v.moveTo(B, from)
+	case "c":
+		B.Delete(from, to-from)
+		B.Cursor = from
+		B.RepaintAfterPrompt()
+		v.startInsert(B, from)
+	case "y":
+		v.moveTo(B, from)
+	}
+}
+
+// command executes the command on the normal state.
+// `next` reads the following key of the command.
+func (v *_ViMode) command(ctx context.Context, B *readline.Buffer, key string, next func() string) readline.Result {
+	count := 0
+	for len(key) == 1 && '0' <= key[0] && key[0] <= '9' && (key != "0" || count > 0) {
+		count = count*10 + int(key[0]-'0')
+		key = next()
+	}
+	repeat := count
+	if repeat <= 0 {
+		repeat = 1
+	}
+	n := len(B.Buffer)
+	changed := true
+	switch key {
+	case "i":
+		v.startInsert(B, B.Cursor)
+	case "a":
+		if n > 0 {
+			B.Cursor++
+		}
+		v.startInsert(B, B.Cursor)
+	case "I":
+		v.startInsert(B, 0)
+	case "A":
+		v.startInsert(B, n)
+	case "x", keys.Delete:
+		v.operate(B, "d", B.Cursor, B.Cursor+repeat)
+	case "X":
+		v.operate(B, "d", B.Cursor-repeat, B.Cursor)
+	case "s":
+		v.operate(B, "c", B.Cursor, B.Cursor+repeat)
+	case "S":
+		v.operate(B, "c", 0, n)
+	case "D":
+		v.operate(B, "d", B.Cursor, n)
+	case "C":
+		v.operate(B, "c", B.Cursor, n)
+	case "Y":
+		v.operate(B, "y", 0, n)
+	case "d", "c", "y":
+		key2 := next()
+		count2 := 0
+		for len(key2) == 1 && '1' <= key2[0] && key2[0] <= '9' || count2 > 0 && key2 == "0" {
+			count2 = count2*10 + int(key2[0]-'0')
+			key2 = next()
+		}
+		if count2 > 0 {
+			repeat *= count2
+		}
+		if key2 == key {
+			v.operate(B, key, 0, n)
+			break
+		}
+		if key == "c" && (key2 == "w" || key2 == "W") && B.Cursor < n &&
+			charClass(B.SubString(B.Cursor, B.Cursor+1), key2 == "W") != 0 {
+			// `cw` works like `ce`
+			key2 = strings.Replace(key2, "w", "e", 1)
+			key2 = strings.Replace(key2, "W", "E", 1)
+		}
+		to, inclusive, ok := v.motion(B, key2, repeat, next)
+		if !ok {
+			B.Out.WriteString("\a")
+			return readline.CONTINUE
+		}
+		from := B.Cursor
+		if inclusive {
+			if to < from {
+				from++
+			} else {
+				to++
+			}
+		}
+		v.operate(B, key, from, to)
+		changed = key != "y"
+	case "p", "P":
+		if v.register == "" {
+			return readline.CONTINUE
+		}
+		pos := B.Cursor
+		if key == "p" && n > 0 {
+			pos++
+		}
+		B.Cursor = pos
+		B.InsertAndRepaint(strings.Repeat(v.register, repeat))
+		v.moveTo(B, B.Cursor-1)
+	case "r":
+		c := next()
+		if B.Cursor+repeat > n || len(c) <= 0 || c[0] < ' ' {
+			return readline.CONTINUE
+		}
+		B.Delete(B.Cursor, repeat)
+		B.InsertString(B.Cursor, strings.Repeat(c, repeat))
+		v.moveTo(B, B.Cursor+repeat-1)
+	case "~":
+		pos := B.Cursor
+		for ; repeat > 0 && pos < n; repeat-- {
+			c := B.SubString(pos, pos+1)
+			t := strings.ToUpper(c)
+			if t == c {
+				t = strings.ToLower(c)
+			}
+			B.Delete(pos, 1)
+			B.InsertString(pos, t)
+			pos++
+		}
+		v.moveTo(B, pos)
+	case "u":
+		cmdUndo(ctx, B)
+		v.moveTo(B, B.Cursor)
+		changed = false
+	case ".":
+		changed = false
+		if v.lastChange == nil {
+			break
+		}
+		for ; repeat > 0; repeat-- {
+			v.replay(ctx, B)
+		}
+	case "j", "k", "+", "-":
+		code := keys.Down
+		if key == "k" || key == "-" {
+			code = keys.Up
+		}
+		changed = false
+		rc := v.editor.LookupCommand(code).Call(ctx, B)
+		if rc == readline.CONTINUE {
+			v.moveTo(B, B.Cursor)
+		}
+		return rc
+	default:
+		changed = false
+		pos, _, ok := v.motion(B, key, count, next)
+		if !ok {
+			B.Out.WriteString("\a")
+			return readline.CONTINUE
+		}
+		v.moveTo(B, pos)
+	}
+	if changed && !v.replaying {
+		v.lastChange = v.keys
+		v.lastInsert = ""
+	}
+	return readline.CONTINUE
+}
+
+// replay executes the last change again.
+func (v *_ViMode) replay(ctx context.Context, B *readline.Buffer) {
+	recorded := v.lastChange
+	next := func() string {
+		if len(recorded) <= 0 {
+			return ""
+		}
+		key := recorded[0]
+		recorded = recorded[1:]
+		return key
+	}
+	v.replaying = true
+	defer func() { v.replaying = false }()
+	v.command(ctx, B, next(), next)
+	if !v.normal {
+		B.InsertAndRepaint(v.lastInsert)
+		v.escape(B)
+		v.moveTo(B, B.Cursor)
+	}
+}
+
+// bind returns the command for the printable key.
+func (v *_ViMode) bind(key string) readline.Command {
+	return &readline.GoCommand{
+		Name: "VI_COMMAND",
+		Func: func(ctx context.Context, B *readline.Buffer) readline.Result {
+			if !v.normal || !v.enabled() {
+				if f, ok := readline.GlobalKeyMap.Lookup(keys.Code(key)); ok {
+					return f.Call(ctx, B)
+				}
+				return readline.SelfInserter(key).Call(ctx, B)
+			}
+			v.keys = []string{key}
+			next := func() string {
+				k, err := kbdMacro.getKey(B)
+				if err != nil || k == keys.Escape || k == keys.CtrlC {
+					return ""
+				}
+				v.keys = append(v.keys, k)
+				return k
+			}
+			return v.command(ctx, B, key, next)
+		},
+	}
+}
+
+func (stream *CmdStreamConsole) setupViMode() {
+	editor := stream.Editor
+	v := &_ViMode{editor: editor}
+	editor.PromptWriter = v.wrapPrompt(editor.PromptWriter)
+	for c := ' '; c <= '~'; c++ {
+		editor.KeyMap.BindKey(keys.Code(string(c)), v.bind(string(c)))
+	}
+	editor.KeyMap.BindKey(keys.Escape, &readline.GoCommand{
+		Name: "VI_ESCAPE",
+		Func: func(ctx context.Context, B *readline.Buffer) readline.Result {
+			if !v.enabled() {
+				if f, ok := readline.GlobalKeyMap.Lookup(keys.Escape); ok {
+					return f.Call(ctx, B)
+				}
+				return readline.CONTINUE
+			}
+			if v.normal {
+				B.Out.WriteString("\a")
+			} else {
+				v.escape(B)
+			}
+			return readline.CONTINUE
+		},
+	})
+	stream.vi = v
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/nyaosorg/go-readline-ny"
 	"github.com/nyaosorg/go-windows-findfile"
 
+	"github.com/nyaosorg/nyagos/internal/clipboard"
 	"github.com/nyaosorg/nyagos/internal/commands"
 	"github.com/nyaosorg/nyagos/internal/completion"
 	"github.com/nyaosorg/nyagos/internal/defined"
@@ -325,6 +326,37 @@ func CmdGetHistory(args []any) []any {
 	return []any{frame.DefaultHistory.Len()}
 }
 
+// CmdGetKillRing returns the table of the texts in the kill-ring
+// (the latest is first) or the N-th text when N is given.
+func CmdGetKillRing(args []any) []any {
+	ring := frame.KillRing()
+	if len(args) >= 1 {
+		if n, ok := toNumber(args[len(args)-1]); ok {
+			if n < 1 || n > len(ring) {
+				return []any{nil}
+			}
+			return []any{ring[n-1]}
+		}
+	}
+	return []any{ring}
+}
+
+// CmdClipboard returns the text in the clipboard without arguments,
+// or sets the text given into the clipboard.
+func CmdClipboard(args []any) []any {
+	if len(args) < 1 {
+		text, err := clipboard.ReadAll()
+		if err != nil {
+			return []any{nil, err.Error()}
+		}
+		return []any{text}
+	}
+	if err := clipboard.WriteAll(fmt.Sprint(args[len(args)-1])); err != nil {
+		return []any{nil, err.Error()}
+	}
+	return []any{true}
+}
+
 func CmdLenHistory(args []any) []any {
 	if frame.DefaultHistory == nil {
 		return []any{}
@@ -441,11 +473,14 @@ func GetOption(args []any) []any {
 		return []any{nil, "too few arguments"}
 	}
 	key := fmt.Sprint(args[1])
+	if ptr, ok := commands.StringOptions.Load(key); ok {
+		return []any{*ptr.V}
+	}
 	ptr, ok := commands.BoolOptions.Load(key)
 	if !ok {
 		return []any{nil, fmt.Sprintf("key: %s: not found", key)}
 	}
-	return []any{*ptr.V}
+	return []any{ptr.Get()}
 }
 
 func SetOption(args []any) []any {
@@ -453,19 +488,25 @@ func SetOption(args []any) []any {
 		return []any{nil, "too few arguments"}
 	}
 	key := fmt.Sprint(args[1])
+	if ptr, ok := commands.StringOptions.Load(key); ok {
+		if err := ptr.Set(fmt.Sprint(args[2])); err != nil {
+			return []any{nil, err.Error()}
+		}
+		return []any{true}
+	}
 	ptr, ok := commands.BoolOptions.Load(key)
 	if !ok || ptr == nil {
 		return []any{nil, "key: %s: not found"}
 	}
 	val := args[2]
 	if val == nil {
-		*ptr.V = false
+		ptr.Set(false)
 	} else if s, ok := val.(string); ok && s == "" {
-		*ptr.V = false
+		ptr.Set(false)
 	} else if b, ok := val.(bool); ok {
-		*ptr.V = b
+		ptr.Set(b)
 	} else {
-		*ptr.V = true
+		ptr.Set(true)
 	}
 	return []any{true}
 }
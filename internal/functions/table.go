@@ -7,6 +7,7 @@ var Table = map[string]func([]interface{}) []interface{}{
 	"bitand":             CmdBitAnd,
 	"bitor":              CmdBitOr,
 	"chdir":              CmdChdir,
+	"clipboard":          CmdClipboard,
 	"commonprefix":       CmdCommonPrefix,
 	"complete_for_files": CmdCompleteForFiles,
 	"dirname":            CmdDirName,
@@ -16,6 +17,7 @@ var Table = map[string]func([]interface{}) []interface{}{
 	"fields":             CmdFields,
 	"getenv":             CmdGetEnv,
 	"gethistory":         CmdGetHistory,
+	"getkillring":        CmdGetKillRing,
 	"getkey":             CmdGetKey,
 	"getkeys":            CmdGetKeys,
 	"getviewwidth":       CmdGetViewWidth,
@@ -109,6 +109,26 @@ cccc`
 	}
 }
 
+func TestSuggest(t *testing.T) {
+	hisObj := &history.Container{}
+	hisObj.PushLine(history.Line{Text: "git commit -a", Dir: "/work"})
+	hisObj.PushLine(history.Line{Text: "git checkout main", Dir: "/tmp"})
+	hisObj.PushLine(history.Line{Text: "git", Dir: "/tmp"})
+
+	if s := hisObj.Suggest("git c", "/work"); s != "ommit -a" {
+		t.Fatalf("Suggest(\"git c\",\"/work\") returned %q", s)
+	}
+	if s := hisObj.Suggest("git c", "/home"); s != "heckout main" {
+		t.Fatalf("Suggest(\"git c\",\"/home\") returned %q", s)
+	}
+	if s := hisObj.Suggest("git", "/tmp"); s != " checkout main" {
+		t.Fatalf("Suggest(\"git\",\"/tmp\") returned %q", s)
+	}
+	if s := hisObj.Suggest("", "/tmp"); s != "" {
+		t.Fatalf("Suggest(\"\",\"/tmp\") returned %q", s)
+	}
+}
+
 // func TestSaveToWriter(t *testing.T) {
 // 	hisObj := &Container{
 // 		[]Line{
@@ -3,6 +3,7 @@ package history
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -59,6 +60,31 @@ func (c *Container) PushLine(row Line) {
 	}
 }
 
+// Suggest returns the rest of the latest history-text which starts with prefix.
+// The lines executed on dir are preferred to the others.
+// When no lines are found, it returns an empty string.
+func (c *Container) Suggest(prefix, dir string) string {
+	if prefix == "" {
+		return ""
+	}
+	other := ""
+	for i := len(c.rows) - 1; i >= 0; i-- {
+		row := &c.rows[i]
+		if len(row.Text) <= len(prefix) ||
+			!strings.HasPrefix(row.Text, prefix) ||
+			strings.ContainsAny(row.Text, "\r\n") {
+			continue
+		}
+		if strings.EqualFold(row.Dir, dir) {
+			return row.Text[len(prefix):]
+		}
+		if other == "" {
+			other = row.Text[len(prefix):]
+		}
+	}
+	return other
+}
+
 // String returns self as printable text
 func (row *Line) String() string {
 	return fmt.Sprintf("%s\t%s\t%s\t%d",
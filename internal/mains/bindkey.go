@@ -15,6 +15,7 @@ import (
 	"github.com/nyaosorg/go-readline-ny"
 	"github.com/nyaosorg/go-readline-ny/nameutils"
 
+	"github.com/nyaosorg/nyagos/internal/commands"
 	"github.com/nyaosorg/nyagos/internal/texts"
 )
 
@@ -188,7 +189,7 @@ func cmdBindKey(L Lua) int {
 		return 1
 	default:
 		val := L.ToString(-1)
-		err := nameutils.BindKeySymbol(readline.GlobalKeyMap, key, val)
+		err := commands.BindKeySymbol(readline.GlobalKeyMap, key, val)
 		if err != nil {
 			return lerror(L, err.Error())
 		}
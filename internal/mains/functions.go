@@ -12,6 +12,7 @@ import (
 	"os"
 
 	"github.com/nyaosorg/nyagos/internal/alias"
+	"github.com/nyaosorg/nyagos/internal/commands"
 	"github.com/nyaosorg/nyagos/internal/shell"
 	"github.com/yuin/gopher-lua"
 )
@@ -103,6 +104,29 @@ func cmdGetAlias(L Lua) int {
 	return 1
 }
 
+// spawnOption reads `env` and `dir` of the table of nyagos.exec.
+// `env={NAME=false}` removes NAME from the environment.
+// It returns nil when neither is given.
+func spawnOption(L Lua, table *lua.LTable) *shell.SpawnOption {
+	env, hasEnv := L.GetField(table, "env").(*lua.LTable)
+	dir, hasDir := L.GetField(table, "dir").(lua.LString)
+	if !hasEnv && !hasDir {
+		return nil
+	}
+	opt := &shell.SpawnOption{Dir: string(dir)}
+	if hasEnv {
+		opt.Env = map[string]string{}
+		env.ForEach(func(key, value lua.LValue) {
+			if value == lua.LFalse {
+				opt.Unset = append(opt.Unset, key.String())
+			} else {
+				opt.Env[key.String()] = value.String()
+			}
+		})
+	}
+	return opt
+}
+
 func cmdExec(L Lua) int {
 	errorlevel := 0
 	var err error
@@ -123,10 +147,14 @@ func cmdExec(L Lua) int {
 			}
 			defer sh.Close()
 		}
-		cmd := sh.Command()
-		defer cmd.Close()
-		cmd.SetArgs(args)
-		errorlevel, err = cmd.Spawnvp(ctx)
+		if opt := spawnOption(L, table); opt != nil {
+			errorlevel, err = sh.SpawnWith(ctx, args, nil, opt)
+		} else {
+			cmd := sh.Command()
+			defer cmd.Close()
+			cmd.SetArgs(args)
+			errorlevel, err = cmd.Spawnvp(ctx)
+		}
 	} else {
 		statement, ok := L.Get(1).(lua.LString)
 		if !ok {
@@ -153,6 +181,45 @@ func cmdExec(L Lua) int {
 	return 2
 }
 
+// cmdRecords returns the output of the built-in command
+// as the array of tables like `--json`.
+func cmdRecords(L Lua) int {
+	var args []string
+	if table, ok := L.Get(1).(*lua.LTable); ok {
+		n := table.Len()
+		args = make([]string, n)
+		for i := 0; i < n; i++ {
+			args[i] = L.GetTable(table, lua.LNumber(i+1)).String()
+		}
+	} else {
+		for i, n := 1, L.GetTop(); i <= n; i++ {
+			args = append(args, L.Get(i).String())
+		}
+	}
+	if len(args) <= 0 {
+		return lerror(L, "nyagos.records: no command name")
+	}
+	ctx, sh := getRegInt(L)
+	if sh == nil {
+		sh = shell.New()
+		sh.SetTag(&luaWrapper{L})
+		defer sh.Close()
+	}
+	cmd := sh.Command()
+	defer cmd.Close()
+	cmd.SetArgs(args)
+	records, err := commands.Records(ctx, cmd)
+	if err != nil {
+		return lerror(L, err.Error())
+	}
+	result := L.NewTable()
+	for i, r := range records {
+		L.SetTable(result, lua.LNumber(i+1), interfaceToLValue(L, r.Map()))
+	}
+	L.Push(result)
+	return 1
+}
+
 func cmdEval(L Lua) int {
 	statement, ok := L.Get(1).(lua.LString)
 	if !ok {
@@ -122,6 +122,7 @@ func Main() error {
 	}
 
 	completion.HookToList = append(completion.HookToList, (&_LuaCallBack{Lua: L}).luaHookForComplete)
+	frame.SuggestHook = (&_LuaCallBack{Lua: L}).luaHookForSuggest
 
 	sh := shell.New()
 	if L != nil {
@@ -176,6 +177,7 @@ func Main() error {
 	}
 
 	var stream1 shell.Stream
+	interactive := false
 	if !commands.ReadStdinAsFile && isatty.IsTerminal(os.Stdin.Fd()) {
 		constream := frame.NewCmdStreamConsole(
 			func(w io.Writer) (int, error) {
@@ -196,11 +198,15 @@ func Main() error {
 		frame.DefaultHistory = constream.History
 		sh.History = constream.History
 		ctx = context.WithValue(ctx, shellKey, sh)
+		interactive = true
 	} else {
 		stream1 = shell.NewCmdStreamFile(os.Stdin)
 	}
 	if L != nil {
-		return sh.ForEver(ctx, &luaFilterStream{Stream: stream1, L: L})
+		stream1 = &luaFilterStream{Stream: stream1, L: L}
+	}
+	if interactive {
+		commands.InteractiveStream = stream1
 	}
 	return sh.ForEver(ctx, stream1)
 }
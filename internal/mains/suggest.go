@@ -0,0 +1,46 @@
+//go:build !vanilla
+// +build !vanilla
+
+package mains
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// luaHookForSuggest calls nyagos.suggest_hook(text) and returns
+// the line to suggest. When the hook is not defined or returns nil,
+// the second value is false and the history is used instead.
+func (L *_LuaCallBack) luaHookForSuggest(ctx context.Context, text string) (string, bool) {
+	nyagosTbl, ok := L.GetGlobal("nyagos").(*lua.LTable)
+	if !ok {
+		return "", false
+	}
+	f, ok := L.GetField(nyagosTbl, "suggest_hook").(*lua.LFunction)
+	if !ok {
+		return "", false
+	}
+
+	defer setContext(getContext(L.Lua), L.Lua)
+	setContext(ctx, L.Lua)
+
+	L.Push(f)
+	L.Push(lua.LString(text))
+	if err := L.PCall(1, 1, nil); err != nil {
+		fmt.Println(err)
+		return "", false
+	}
+	defer L.Pop(1)
+
+	switch value := L.Get(-1).(type) {
+	case lua.LString:
+		return string(value), true
+	case lua.LBool:
+		if !value {
+			return "", true
+		}
+	}
+	return "", false
+}
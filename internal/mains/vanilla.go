@@ -13,6 +13,7 @@ import (
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
 
+	"github.com/nyaosorg/nyagos/internal/commands"
 	"github.com/nyaosorg/nyagos/internal/frame"
 	"github.com/nyaosorg/nyagos/internal/functions"
 	"github.com/nyaosorg/nyagos/internal/shell"
@@ -105,6 +106,7 @@ func Main() error {
 		stream1 = constream
 		frame.DefaultHistory = constream.History
 		sh.History = constream.History
+		commands.InteractiveStream = stream1
 	} else {
 		stream1 = shell.NewCmdStreamFile(os.Stdin)
 	}
@@ -0,0 +1,39 @@
+package nodos
+
+import (
+	"time"
+)
+
+// TrashEntry is a file or a directory in the trash can.
+type TrashEntry struct {
+	// Name is the name of the entry in the trash can.
+	Name string
+	// Path is the original full path.
+	Path      string
+	DeletedAt time.Time
+
+	body string // the path of the file moved into the trash can
+	info string // the path of the file which has Path and DeletedAt
+}
+
+// MoveToTrash moves the file or the directory to the trash can:
+// the recycle bin on Windows, the freedesktop.org Trash on others.
+func MoveToTrash(path string) error {
+	return moveToTrash(path)
+}
+
+// ListTrash returns the entries in the trash can.
+func ListTrash() ([]*TrashEntry, error) {
+	return listTrash()
+}
+
+// RestoreTrash moves the entry back to the original path.
+// It fails when the original path exists.
+func RestoreTrash(e *TrashEntry) error {
+	return restoreTrash(e)
+}
+
+// EmptyTrash removes all entries in the trash can.
+func EmptyTrash() error {
+	return emptyTrash()
+}
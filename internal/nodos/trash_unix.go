@@ -0,0 +1,296 @@
+//go:build !windows
+// +build !windows
+
+package nodos
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The trash can of the freedesktop.org Trash specification
+// https://specifications.freedesktop.org/trash-spec/
+
+const trashInfoSuffix = ".trashinfo"
+
+const trashDateLayout = "2006-01-02T15:04:05"
+
+// trashCan is a directory which has files/ and info/
+type trashCan struct {
+	dir string
+	// top is the top directory of the mounted device,
+	// which the relative Path in info files is based on.
+	top string
+}
+
+func homeTrash() *trashCan {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(GetHome(), ".local", "share")
+	}
+	return &trashCan{dir: filepath.Join(dataHome, "Trash"), top: "/"}
+}
+
+func (t *trashCan) files() string { return filepath.Join(t.dir, "files") }
+func (t *trashCan) infos() string { return filepath.Join(t.dir, "info") }
+
+func (t *trashCan) prepare() error {
+	if err := os.MkdirAll(t.files(), 0700); err != nil {
+		return err
+	}
+	return os.MkdirAll(t.infos(), 0700)
+}
+
+func deviceOf(path string) (uint64, error) {
+	stat, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev), nil
+	}
+	return 0, errors.New("device unknown")
+}
+
+// topDirectory returns the mount point of the device which path is on.
+func topDirectory(path string, device uint64) string {
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		if d, err := deviceOf(parent); err != nil || d != device {
+			return path
+		}
+		path = parent
+	}
+}
+
+// topTrashes returns the trash cans on the top directory.
+// $top/.Trash/$uid is used when the administrator made $top/.Trash
+// with the sticky bit, otherwise $top/.Trash-$uid.
+func topTrashes(top string) []*trashCan {
+	uid := strconv.Itoa(os.Getuid())
+	result := []*trashCan{}
+	if stat, err := os.Lstat(filepath.Join(top, ".Trash")); err == nil &&
+		stat.IsDir() && stat.Mode()&os.ModeSticky != 0 {
+		result = append(result, &trashCan{dir: filepath.Join(top, ".Trash", uid), top: top})
+	}
+	return append(result, &trashCan{dir: filepath.Join(top, ".Trash-"+uid), top: top})
+}
+
+// trashFor returns the trash can on the same device as path,
+// because files can not be renamed across devices.
+func trashFor(path string) (*trashCan, error) {
+	home := homeTrash()
+	if err := home.prepare(); err != nil {
+		return nil, err
+	}
+	device, err := deviceOf(path)
+	if err != nil {
+		return nil, err
+	}
+	if d, err := deviceOf(home.dir); err == nil && d == device {
+		return home, nil
+	}
+	var lastErr error
+	for _, t := range topTrashes(topDirectory(path, device)) {
+		if lastErr = t.prepare(); lastErr == nil {
+			return t, nil
+		}
+	}
+	return nil, lastErr
+}
+
+func moveToTrash(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	t, err := trashFor(path)
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(path)
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s.%d", base, n)
+		}
+		// The info file is made first to reserve the name.
+		info := filepath.Join(t.infos(), name+trashInfoSuffix)
+		fd, err := os.OpenFile(info, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		body := filepath.Join(t.files(), name)
+		if _, err := os.Lstat(body); err == nil {
+			fd.Close()
+			os.Remove(info)
+			continue
+		}
+		fmt.Fprintf(fd, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: path}).EscapedPath(),
+			time.Now().Format(trashDateLayout))
+		if err := fd.Close(); err != nil {
+			os.Remove(info)
+			return err
+		}
+		if err := os.Rename(path, body); err != nil {
+			os.Remove(info)
+			return err
+		}
+		return nil
+	}
+}
+
+// mountPoints returns the mounted directories listed in /proc/mounts.
+// It returns nil where /proc does not exist.
+func mountPoints() []string {
+	fd, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+	defer fd.Close()
+	result := []string{}
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 2 {
+			result = append(result, strings.ReplaceAll(fields[1], `\040`, " "))
+		}
+	}
+	return result
+}
+
+// TrashMountPoints returns the top directories whose trash cans are
+// listed and emptied with the home trash. Tests replace it so that
+// the real trash cans on the other devices are not touched.
+var TrashMountPoints = mountPoints
+
+// trashCans returns the home trash and the trash cans which exist
+// on the top directories of the mounted devices.
+func trashCans() []*trashCan {
+	result := []*trashCan{homeTrash()}
+	for _, top := range TrashMountPoints() {
+		for _, t := range topTrashes(top) {
+			if stat, err := os.Stat(t.infos()); err == nil && stat.IsDir() {
+				result = append(result, t)
+			}
+		}
+	}
+	return result
+}
+
+func (t *trashCan) readInfo(name string) (*TrashEntry, error) {
+	info := filepath.Join(t.infos(), name+trashInfoSuffix)
+	fd, err := os.Open(info)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	e := &TrashEntry{
+		Name: name,
+		body: filepath.Join(t.files(), name),
+		info: info,
+	}
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Path":
+			if p, err := url.PathUnescape(value); err == nil {
+				e.Path = p
+			} else {
+				e.Path = value
+			}
+			if !filepath.IsAbs(e.Path) {
+				e.Path = filepath.Join(t.top, e.Path)
+			}
+		case "DeletionDate":
+			e.DeletedAt, _ = time.ParseInLocation(trashDateLayout, value, time.Local)
+		}
+	}
+	if e.Path == "" {
+		return nil, fmt.Errorf("%s: Path not found", info)
+	}
+	// DeletionDate has only seconds. The time of the info file is used
+	// to order the entries deleted in the same second.
+	if stat, err := fd.Stat(); err == nil && stat.ModTime().Truncate(time.Second).Equal(e.DeletedAt) {
+		e.DeletedAt = stat.ModTime()
+	}
+	return e, sc.Err()
+}
+
+func listTrash() ([]*TrashEntry, error) {
+	result := []*TrashEntry{}
+	for _, t := range trashCans() {
+		files, err := os.ReadDir(t.infos())
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return result, err
+		}
+		for _, f := range files {
+			name := f.Name()
+			if !strings.HasSuffix(name, trashInfoSuffix) {
+				continue
+			}
+			e, err := t.readInfo(strings.TrimSuffix(name, trashInfoSuffix))
+			if err != nil {
+				continue
+			}
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func restoreTrash(e *TrashEntry) error {
+	if _, err := os.Lstat(e.Path); err == nil {
+		return fmt.Errorf("%s: already exists", e.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0777); err != nil {
+		return err
+	}
+	if err := os.Rename(e.body, e.Path); err != nil {
+		return err
+	}
+	return os.Remove(e.info)
+}
+
+func emptyTrash() error {
+	for _, t := range trashCans() {
+		for _, dir := range []string{t.files(), t.infos()} {
+			files, err := os.ReadDir(dir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			for _, f := range files {
+				if err := os.RemoveAll(filepath.Join(dir, f.Name())); err != nil {
+					return err
+				}
+			}
+		}
+		os.Remove(filepath.Join(t.dir, "directorysizes"))
+	}
+	return nil
+}
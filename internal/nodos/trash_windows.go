@@ -0,0 +1,196 @@
+package nodos
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSHFileOperationW = shell32.NewProc("SHFileOperationW")
+var procSHEmptyRecycleBinW = shell32.NewProc("SHEmptyRecycleBinW")
+
+type _ShFileOpStruct struct {
+	Hwnd                 uintptr
+	Func                 uint32
+	From                 *uint16
+	To                   *uint16
+	Flags                uint16
+	AnyOperationsAborted int32
+	NameMappings         uintptr
+	ProgressTitle        *uint16
+}
+
+const (
+	_FO_DELETE          = 3
+	_FOF_SILENT         = 0x0004
+	_FOF_NOCONFIRMATION = 0x0010
+	_FOF_ALLOWUNDO      = 0x0040
+	_FOF_NOERRORUI      = 0x0400
+
+	_SHERB_NOCONFIRMATION = 0x1
+	_SHERB_NOPROGRESSUI   = 0x2
+	_SHERB_NOSOUND        = 0x4
+)
+
+func moveToTrash(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	// pFrom is terminated by double nulls.
+	from := utf16.Encode([]rune(path + "\x00\x00"))
+	op := _ShFileOpStruct{
+		Func:  _FO_DELETE,
+		From:  &from[0],
+		Flags: _FOF_ALLOWUNDO | _FOF_NOCONFIRMATION | _FOF_SILENT | _FOF_NOERRORUI,
+	}
+	rc, _, _ := procSHFileOperationW.Call(uintptr(unsafe.Pointer(&op)))
+	if rc != 0 {
+		return fmt.Errorf("%s: SHFileOperation failed (0x%X)", path, rc)
+	}
+	if op.AnyOperationsAborted != 0 {
+		return fmt.Errorf("%s: aborted", path)
+	}
+	return nil
+}
+
+// recycleBins returns $Recycle.Bin\{SID} of the drives.
+func recycleBins() ([]string, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	sid := user.User.Sid.String()
+	drives, err := windows.GetLogicalDrives()
+	if err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for i := 0; i < 26; i++ {
+		if drives&(1<<uint(i)) == 0 {
+			continue
+		}
+		dir := fmt.Sprintf(`%c:\$Recycle.Bin\%s`, 'A'+i, sid)
+		if stat, err := os.Stat(dir); err == nil && stat.IsDir() {
+			result = append(result, dir)
+		}
+	}
+	return result, nil
+}
+
+// readRecycleInfo reads $I... which has the original path.
+// Version 1 (Vista-8.1) has the path of the fixed 260 characters,
+// version 2 (10-) has the length before the path.
+func readRecycleInfo(path string) (string, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if len(data) < 24 {
+		return "", time.Time{}, errors.New("too short")
+	}
+	version := binary.LittleEndian.Uint64(data[0:])
+	ft := windows.Filetime{
+		LowDateTime:  binary.LittleEndian.Uint32(data[16:]),
+		HighDateTime: binary.LittleEndian.Uint32(data[20:]),
+	}
+	var raw []byte
+	switch version {
+	case 1:
+		raw = data[24:]
+	case 2:
+		if len(data) < 28 {
+			return "", time.Time{}, errors.New("too short")
+		}
+		raw = data[28:]
+	default:
+		return "", time.Time{}, fmt.Errorf("unknown version %d", version)
+	}
+	name := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		c := binary.LittleEndian.Uint16(raw[i:])
+		if c == 0 {
+			break
+		}
+		name = append(name, c)
+	}
+	return string(utf16.Decode(name)), time.Unix(0, ft.Nanoseconds()), nil
+}
+
+func listTrash() ([]*TrashEntry, error) {
+	bins, err := recycleBins()
+	if err != nil {
+		return nil, err
+	}
+	result := []*TrashEntry{}
+	for _, dir := range bins {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := f.Name()
+			if !strings.HasPrefix(name, "$I") {
+				continue
+			}
+			path, deletedAt, err := readRecycleInfo(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			body := filepath.Join(dir, "$R"+name[2:])
+			if _, err := os.Lstat(body); err != nil {
+				continue
+			}
+			result = append(result, &TrashEntry{
+				Name:      "$R" + name[2:],
+				Path:      path,
+				DeletedAt: deletedAt,
+				body:      body,
+				info:      filepath.Join(dir, name),
+			})
+		}
+	}
+	return result, nil
+}
+
+func restoreTrash(e *TrashEntry) error {
+	if _, err := os.Lstat(e.Path); err == nil {
+		return fmt.Errorf("%s: already exists", e.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0777); err != nil {
+		return err
+	}
+	src, err := windows.UTF16PtrFromString(e.body)
+	if err != nil {
+		return err
+	}
+	dst, err := windows.UTF16PtrFromString(e.Path)
+	if err != nil {
+		return err
+	}
+	if err := windows.MoveFileEx(src, dst, windows.MOVEFILE_COPY_ALLOWED); err != nil {
+		return err
+	}
+	return os.Remove(e.info)
+}
+
+func emptyTrash() error {
+	rc, _, _ := procSHEmptyRecycleBinW.Call(0, 0,
+		_SHERB_NOCONFIRMATION|_SHERB_NOPROGRESSUI|_SHERB_NOSOUND)
+	// E_UNEXPECTED is returned when the recycle bin is empty already.
+	if rc != S_OK && uint32(rc) != 0x8000FFFF {
+		return fmt.Errorf("SHEmptyRecycleBin failed (0x%X)", rc)
+	}
+	return nil
+}
@@ -85,6 +85,9 @@ type Cmd struct {
 	UseShellExecute bool
 	Closers         []io.Closer
 	env             map[string]string
+	envUnset        map[string]bool
+	envClear        bool
+	dir             string
 	OnBackExec      func(int)
 	OnBackDone      func(int)
 }
@@ -146,6 +149,9 @@ func (cmd *Cmd) Getenv(key string) string {
 			return val
 		}
 	}
+	if cmd.envClear || cmd.envUnset[envName(key)] {
+		return ""
+	}
 	return os.Getenv(key)
 }
 
@@ -157,21 +163,10 @@ func (cmd *Cmd) Setenv(key, val string) {
 }
 
 func (cmd *Cmd) DumpEnv() []string {
-	if cmd.env == nil {
+	if cmd.env == nil && cmd.envUnset == nil && !cmd.envClear {
 		return nil
 	}
-	osEnv := os.Environ()
-	result := make([]string, 0, len(cmd.env)+len(osEnv))
-	for _, equation := range osEnv {
-		eqIndex := strings.IndexRune(equation, '=')
-		if _, ok := cmd.env[strings.ToUpper(equation[:eqIndex])]; !ok {
-			result = append(result, equation)
-		}
-	}
-	for key, val := range cmd.env {
-		result = append(result, key+"="+val)
-	}
-	return result
+	return mergeEnv(os.Environ(), cmd.env, cmd.envUnset, cmd.envClear)
 }
 
 var LookCurdirOrder = nodos.LookCurdirFirst
@@ -336,9 +331,14 @@ func (cmd *Cmd) spawnvpSilent(ctx context.Context) (int, error) {
 		print("spawnvpSilent('", cmd.args[0], "')\n")
 	}
 
-	// aliases and lua-commands
-	if errorlevel, done, err := cmd.LineHook(ctx, cmd); done || err != nil {
-		return errorlevel, err
+	if cmd.dir == "" {
+		// aliases and lua-commands
+		if errorlevel, done, err := cmd.LineHook(ctx, cmd); done || err != nil {
+			return errorlevel, err
+		}
+	} else if !filepath.IsAbs(cmd.args[0]) && strings.ContainsAny(cmd.args[0], `/\`) {
+		// The relative path of the command is from the working directory of it.
+		cmd.args = append([]string{filepath.Join(cmd.dir, cmd.args[0])}, cmd.args[1:]...)
 	}
 
 	// command not found hook
@@ -457,12 +457,7 @@ func (cmd *Cmd) Spawnvp(ctx context.Context) (int, error) {
 }
 
 func (sh *Shell) Spawnlpe(ctx context.Context, args, rawargs []string, env map[string]string) (int, error) {
-	cmd := sh.Command()
-	defer cmd.Close()
-	cmd.SetArgs(args)
-	cmd.SetRawArgs(rawargs)
-	cmd.env = env
-	return cmd.Spawnvp(ctx)
+	return sh.SpawnWith(ctx, args, rawargs, &SpawnOption{Env: env})
 }
 
 type _TmpCloser struct {
@@ -41,7 +41,7 @@ func (cmd *Cmd) startProcess(ctx context.Context) (int, error) {
 		if _truepath, err := filepath.EvalSymlinks(truepath); err == nil {
 			truepath = _truepath
 		}
-		pid, err := su.ShellExecute("open", truepath, cmdline, "")
+		pid, err := su.ShellExecute("open", truepath, cmdline, cmd.dir)
 		if err == nil && pid != 0 && cmd.OnBackExec != nil {
 			cmd.OnBackExec(pid)
 			if cmd.OnBackDone != nil {
@@ -65,7 +65,9 @@ func (cmd *Cmd) startProcess(ctx context.Context) (int, error) {
 	if closer, err := nodos.ChangeConsoleMode(windows.Stdout); err == nil {
 		defer closer()
 	}
-	if UseSourceRunBatch {
+	// The changes by the batchfile are not imported
+	// when it runs in another directory.
+	if UseSourceRunBatch && cmd.dir == "" {
 		lowerName := strings.ToLower(cmd.args[0])
 		if strings.HasSuffix(lowerName, ".cmd") || strings.HasSuffix(lowerName, ".bat") {
 			rawargs := cmd.RawArgs()
@@ -91,6 +93,7 @@ func (cmd *Cmd) startProcess(ctx context.Context) (int, error) {
 
 	procAttr := &os.ProcAttr{
 		Env:   cmd.DumpEnv(),
+		Dir:   cmd.dir,
 		Files: cmd.Stdio[:],
 		Sys:   &syscall.SysProcAttr{CmdLine: cmdline},
 	}
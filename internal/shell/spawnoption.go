@@ -0,0 +1,111 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// SpawnOption is the environment and the working directory
+// of the command executed by SpawnWith.
+type SpawnOption struct {
+	// Env is the variables to set.
+	Env map[string]string
+	// Unset is the names of the variables to remove.
+	Unset []string
+	// Clear starts the command with the empty environment except Env.
+	Clear bool
+	// Dir is the working directory of the command.
+	Dir string
+}
+
+// envName returns the name to compare variables,
+// which is case-insensitive on Windows.
+func envName(name string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// mergeEnv returns the environment of `NAME=VALUE` which is osEnv
+// overridden by env and without the names in unset.
+func mergeEnv(osEnv []string, env map[string]string, unset map[string]bool, clear bool) []string {
+	result := make([]string, 0, len(env)+len(osEnv))
+	overridden := make(map[string]bool, len(env))
+	for key := range env {
+		overridden[envName(key)] = true
+	}
+	if !clear {
+		for _, equation := range osEnv {
+			// Windows has the variables like `=C:=C:\`
+			eqIndex := strings.IndexRune(equation[1:], '=') + 1
+			if eqIndex <= 0 {
+				continue
+			}
+			name := envName(equation[:eqIndex])
+			if overridden[name] || unset[name] {
+				continue
+			}
+			result = append(result, equation)
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		result = append(result, key+"="+env[key])
+	}
+	return result
+}
+
+func (opt *SpawnOption) apply(cmd *Cmd) {
+	cmd.env = opt.Env
+	if len(opt.Unset) > 0 {
+		cmd.envUnset = make(map[string]bool, len(opt.Unset))
+		for _, name := range opt.Unset {
+			cmd.envUnset[envName(name)] = true
+		}
+	}
+	cmd.envClear = opt.Clear
+	cmd.dir = opt.Dir
+}
+
+// Environ returns the environment which SpawnWith gives to the command.
+func (opt *SpawnOption) Environ() []string {
+	cmd := &Cmd{}
+	opt.apply(cmd)
+	return mergeEnv(os.Environ(), cmd.env, cmd.envUnset, cmd.envClear)
+}
+
+// SpawnWith executes the command with the environment and the working directory.
+// When rawargs is nil, they are made from args.
+// With the working directory, only the executables are called
+// (not the built-in commands nor aliases) because the working directory
+// of nyagos itself is shared by the commands running at the same time.
+func (sh *Shell) SpawnWith(ctx context.Context, args, rawargs []string, opt *SpawnOption) (int, error) {
+	cmd := sh.Command()
+	defer cmd.Close()
+	if rawargs != nil {
+		cmd.SetRawArgs(rawargs)
+	}
+	cmd.SetArgs(args)
+	if opt != nil {
+		opt.apply(cmd)
+		if opt.Dir != "" {
+			stat, err := os.Stat(opt.Dir)
+			if err != nil {
+				return 1, err
+			}
+			if !stat.IsDir() {
+				return 1, fmt.Errorf("%s: not a directory", opt.Dir)
+			}
+		}
+	}
+	return cmd.Spawnvp(ctx)
+}
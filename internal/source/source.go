@@ -32,36 +32,50 @@ func readEnv(scan scannerT, verbose io.Writer) (int, error) {
 	errorlevel := -1
 	curEnv := getCurrentEnvs()
 
+	type envT struct{ name, value string }
+	envs := []*envT{}
 	for scan.Scan() {
+		if len(envs) > 0 && isContinuedEnv(scan.Text()) {
+			// the value of several lines
+			last := envs[len(envs)-1]
+			last.value = last.value + "\n" + scan.Text()
+			continue
+		}
 		line := strings.TrimSpace(scan.Text())
 		eqlPos := strings.Index(line, "=")
 		if eqlPos > 0 {
-			left := line[:eqlPos]
-			right := line[eqlPos+1:]
-			delete(curEnv, strings.ToUpper(left))
-
-			if left == "ERRORLEVEL_" {
-				value, err := strconv.ParseInt(right, 10, 32)
-				if err != nil {
-					if verbose != nil {
-						fmt.Fprintf(verbose, "Could not read ERRORLEVEL(%s)\n", right)
-					}
-				} else {
-					errorlevel = int(value)
-				}
-			} else {
-				orig := os.Getenv(left)
+			envs = append(envs, &envT{name: line[:eqlPos], value: line[eqlPos+1:]})
+		}
+	}
+	for _, env := range envs {
+		left := env.name
+		right := env.value
+		delete(curEnv, strings.ToUpper(left))
+
+		if left == "ERRORLEVEL_" {
+			value, err := strconv.ParseInt(right, 10, 32)
+			if err != nil {
 				if verbose != nil {
-					fmt.Fprintf(verbose, "%s=%s\n", left, right)
-				}
-				if orig != right {
-					// fmt.Fprintf(os.Stderr, "%s:=%s\n", left, right)
-					os.Setenv(left, right)
+					fmt.Fprintf(verbose, "Could not read ERRORLEVEL(%s)\n", right)
 				}
+			} else {
+				errorlevel = int(value)
+			}
+		} else if !ignoredEnv[left] {
+			orig := os.Getenv(left)
+			if verbose != nil {
+				fmt.Fprintf(verbose, "%s=%s\n", left, right)
+			}
+			if orig != right {
+				// fmt.Fprintf(os.Stderr, "%s:=%s\n", left, right)
+				os.Setenv(left, right)
 			}
 		}
 	}
 	for _, name := range curEnv {
+		if ignoredEnv[name] {
+			continue
+		}
 		// println("unsetenv", name)
 		os.Unsetenv(name)
 	}
@@ -10,17 +10,62 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+// ignoredEnv are the variables which the shell changes by itself.
+var ignoredEnv = map[string]bool{
+	"_":      true,
+	"OLDPWD": true,
+	"PWD":    true,
+	"SHLVL":  true,
+}
+
+var rxEnvLine = regexp.MustCompile(`^[^=\s]+=`)
+
+// isContinuedEnv reports whether the line printed by `env` is
+// the rest of the value of the previous line. It is used only when
+// `env -0` is not supported.
+func isContinuedEnv(line string) bool {
+	return !rxEnvLine.MatchString(line)
+}
+
+// envScanner returns the lines of the working directory and ERRORLEVEL_,
+// and the variables printed by `env -0` (or `env` when -0 is not supported)
+type envScanner struct {
+	tokens []string
+	text   string
+}
+
+func (e *envScanner) Scan() bool {
+	if len(e.tokens) <= 0 {
+		return false
+	}
+	e.text = e.tokens[0]
+	e.tokens = e.tokens[1:]
+	return true
+}
+
+func (e *envScanner) Text() string { return e.text }
+
+func (e *envScanner) Err() error { return nil }
+
 func loadTmpFile(fname string, verbose io.Writer) (int, error) {
-	fp, err := os.Open(fname)
+	data, err := os.ReadFile(fname)
 	if err != nil {
 		return -1, err
 	}
-	defer fp.Close()
-
-	scan := bufio.NewScanner(fp)
+	tokens := strings.SplitN(string(data), "\n", 3)
+	if len(tokens) >= 3 {
+		rest := tokens[2]
+		if strings.Contains(rest, "\x00") {
+			tokens = append(tokens[:2], strings.Split(strings.TrimSuffix(rest, "\x00"), "\x00")...)
+		} else {
+			tokens = append(tokens[:2], strings.Split(strings.TrimSuffix(rest, "\n"), "\n")...)
+		}
+	}
+	scan := &envScanner{tokens: tokens}
 	if err := readPwd(scan, verbose); err != nil {
 		return -1, err
 	}
@@ -28,17 +73,22 @@ func loadTmpFile(fname string, verbose io.Writer) (int, error) {
 }
 
 func (system *System) run() (int, error) {
+	return system.runWith("/bin/sh")
+}
+
+func (system *System) runWith(shell string) (int, error) {
 	args := []string{
-		"/bin/sh",
+		shell,
 		"-c",
 		system.Cmdline,
 	}
 	cmd := &exec.Cmd{
-		Path:   "/bin/sh",
+		Path:   shell,
 		Args:   args,
 		Stdin:  system.Stdin,
 		Stdout: system.Stdout,
 		Stderr: system.Stderr,
+		Env:    system.Env,
 	}
 	if cmd.Stdin == nil {
 		cmd.Stdin = os.Stdin
@@ -64,27 +114,58 @@ func (system *System) run() (int, error) {
 	return cmd.ProcessState.ExitCode(), nil
 }
 
-func (batch *Batch) call(tmpfile string) (int, error) {
-	var cmdline strings.Builder
+// shQuote encloses s with single quotations for sh.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
 
-	cmdline.WriteByte('.')
+// findShell returns the shell written in the shebang line of the script
+// when it is compatible with sh. Otherwise, it returns /bin/sh.
+func findShell(script string) string {
+	fd, err := os.Open(script)
+	if err != nil {
+		return "/bin/sh"
+	}
+	defer fd.Close()
+	line, _ := bufio.NewReader(fd).ReadString('\n')
+	if !strings.HasPrefix(line, "#!") {
+		return "/bin/sh"
+	}
+	field := strings.Fields(line[2:])
+	if len(field) >= 2 && filepath.Base(field[0]) == "env" {
+		field = field[1:]
+	}
+	if len(field) >= 1 {
+		switch filepath.Base(field[0]) {
+		case "bash", "zsh", "ksh", "dash":
+			if path, err := exec.LookPath(filepath.Base(field[0])); err == nil {
+				return path
+			}
+		}
+	}
+	return "/bin/sh"
+}
 
-	if fullpath, err := filepath.Abs(strings.ReplaceAll(batch.Args[0], `"`, ``)); err == nil {
-		fmt.Fprintf(&cmdline, ` "%s"`, fullpath)
-	} else {
-		cmdline.WriteByte(' ')
-		cmdline.WriteString(batch.Args[0])
+// call sources the script with the shell and writes the working directory,
+// the environment variables and the exit status to tmpfile
+// even when the script calls `exit`. The exit status is read as ERRORLEVEL_.
+func (batch *Batch) call(tmpfile string) (int, error) {
+	script := strings.ReplaceAll(batch.Args[0], `"`, ``)
+	if fullpath, err := filepath.Abs(script); err == nil {
+		script = fullpath
 	}
+	var cmdline strings.Builder
 
+	fmt.Fprintf(&cmdline, "__nyagos_tmp=%s\n", shQuote(tmpfile))
+	cmdline.WriteString(`trap '__nyagos_rc=$? ; (pwd ; echo "ERRORLEVEL_=$__nyagos_rc" ; env -0 2>/dev/null || env) > "$__nyagos_tmp" ; exit 0' EXIT`)
+	cmdline.WriteString("\nset --")
 	for _, arg1 := range batch.Args[1:] {
 		cmdline.WriteByte(' ')
 		cmdline.WriteString(arg1)
 	}
-	cmdline.WriteString(` ; (pwd ; env) > '`)
-	cmdline.WriteString(tmpfile)
-	cmdline.WriteString(`'`)
+	fmt.Fprintf(&cmdline, "\n. %s\n", shQuote(script))
 
-	return System{
+	system := &System{
 		Cmdline: cmdline.String(),
 		Stdin:   batch.Stdin,
 		Stdout:  batch.Stdout,
@@ -92,5 +173,6 @@ func (batch *Batch) call(tmpfile string) (int, error) {
 		Env:     batch.Env,
 		OnExec:  batch.OnExec,
 		OnDone:  batch.OnDone,
-	}.Run()
+	}
+	return system.runWith(findShell(script))
 }
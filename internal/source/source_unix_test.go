@@ -0,0 +1,64 @@
+//go:build !windows
+// +build !windows
+
+package source_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nyaosorg/nyagos/internal/source"
+)
+
+func TestShellScriptCall(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd():%s", err.Error())
+	}
+	defer os.Chdir(wd)
+
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	scriptPath := filepath.Join(tmpDir, "script.sh")
+	err = os.WriteFile(scriptPath, []byte("cd \""+tmpDir+"\"\n"+
+		"export SCRIPTTEST=\"$1\"\n"+
+		"export MULTILINE='a\nb=c'\n"+
+		"unset UNSETTEST\n"+
+		"exit 1\n"), 0666)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	t.Setenv("SCRIPTTEST", "FAILURE")
+	t.Setenv("UNSETTEST", "FAILURE")
+	rc, err := source.ExecBatch(
+		[]string{scriptPath, `"SUCCESS 1"`},
+		io.Discard,
+		false,
+		os.Stdin,
+		os.Stdout,
+		os.Stderr,
+		nil)
+	if err != nil {
+		t.Fatalf("ExecBatch(\"%s\")=%d,%s", scriptPath, rc, err.Error())
+	}
+	if rc != 1 {
+		t.Fatalf("ExecBatch(\"%s\")=%d,nil", scriptPath, rc)
+	}
+	if value := os.Getenv("SCRIPTTEST"); value != "SUCCESS 1" {
+		t.Fatalf("SCRIPTTEST=\"%s\" (expect \"SUCCESS 1\")", value)
+	}
+	if value := os.Getenv("MULTILINE"); value != "a\nb=c" {
+		t.Fatalf("MULTILINE=\"%s\"", value)
+	}
+	if _, ok := os.LookupEnv("UNSETTEST"); ok {
+		t.Fatal("UNSETTEST is not removed")
+	}
+	if wd, err := os.Getwd(); err != nil || wd != tmpDir {
+		t.Fatalf("os.Getwd() != \"%s\"", tmpDir)
+	}
+}
@@ -11,6 +11,15 @@ import (
 	"github.com/nyaosorg/go-windows-netresource"
 )
 
+// ignoredEnv are the variables which are not imported from CMD.EXE
+var ignoredEnv = map[string]bool{}
+
+// isContinuedEnv is always false because `set` of CMD.EXE
+// prints a variable in one line.
+func isContinuedEnv(line string) bool {
+	return false
+}
+
 // loadTmpFile - read update the current-directory and environment-variables from tmp-file.
 func loadTmpFile(fname string, verbose io.Writer) (int, error) {
 	fp, err := os.Open(fname)
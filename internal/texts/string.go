@@ -62,3 +62,25 @@ func FirstWord(line string) string {
 	str, _ := quotedWordCutter(reader)
 	return str
 }
+
+// OpenQuote returns the quotation mark which is not closed at the end of s.
+// When all quotations are closed, it returns '\000'.
+func OpenQuote(s string) rune {
+	quote := nulquote
+	yenCount := 0
+	for _, ch := range s {
+		if yenCount%2 == 0 {
+			if quote == nulquote && (ch == '"' || ch == '\'') {
+				quote = ch
+			} else if quote != nulquote && ch == quote {
+				quote = nulquote
+			}
+		}
+		if ch == '\\' {
+			yenCount++
+		} else {
+			yenCount = 0
+		}
+	}
+	return quote
+}
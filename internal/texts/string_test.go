@@ -27,3 +27,18 @@ func TestSplitLikeShellString(t *testing.T) {
 		t.Error("Case-3: failed")
 	}
 }
+
+func TestOpenQuote(t *testing.T) {
+	if q := texts.OpenQuote(`echo "a b" 'c d'`); q != '\000' {
+		t.Errorf("Case-1: failed (%q)", q)
+	}
+	if q := texts.OpenQuote(`echo "a 'b`); q != '"' {
+		t.Errorf("Case-2: failed (%q)", q)
+	}
+	if q := texts.OpenQuote(`echo 'a "b`); q != '\'' {
+		t.Errorf("Case-3: failed (%q)", q)
+	}
+	if q := texts.OpenQuote(`echo \"a`); q != '\000' {
+		t.Errorf("Case-4: failed (%q)", q)
+	}
+}